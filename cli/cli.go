@@ -24,4 +24,5 @@ func init() {
 	initInitCmd()
 	initBuildCmd()
 	initTemplateCmd()
+	initMigrateCmd()
 }
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Dankko0w0/gospike/confManager"
+	"github.com/Dankko0w0/gospike/db"
+	"github.com/Dankko0w0/gospike/db/migrate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrationsDir  string
+	migrateDB      string
+	migrationTable string
+)
+
+func initMigrateCmd() {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage database schema migrations",
+		Long: `Create and apply versioned SQL migrations.
+Migrations are <version>_<name>.up.sql / .down.sql files, applied to a connection
+configured under databases.<name> in the config file.`,
+	}
+
+	migrateCmd.PersistentFlags().StringVar(&migrationsDir, "dir", "migrations", "migrations directory")
+	migrateCmd.PersistentFlags().StringVarP(&migrateDB, "database", "d", "main", "database connection name")
+	migrateCmd.PersistentFlags().StringVar(&migrationTable, "table", migrate.DefaultTable, "schema version table")
+
+	// 添加子命令
+	migrateCmd.AddCommand(
+		&cobra.Command{
+			Use:   "create [name]",
+			Short: "Create a new pair of migration files",
+			Args:  cobra.ExactArgs(1),
+			RunE:  runMigrateCreate,
+		},
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply all pending migrations",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(ctx context.Context, m *migrate.Migrator, args []string) error {
+				return m.Up(ctx)
+			}),
+		},
+		&cobra.Command{
+			Use:   "down [steps]",
+			Short: "Roll back the last applied migrations (default 1)",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: withMigrator(func(ctx context.Context, m *migrate.Migrator, args []string) error {
+				steps := 1
+				if len(args) == 1 {
+					n, err := strconv.Atoi(args[0])
+					if err != nil || n < 1 {
+						return fmt.Errorf("invalid steps %q", args[0])
+					}
+					steps = n
+				}
+				return m.Down(ctx, steps)
+			}),
+		},
+		&cobra.Command{
+			Use:   "goto [version]",
+			Short: "Migrate up or down to the given version (0 rolls back everything)",
+			Args:  cobra.ExactArgs(1),
+			RunE: withMigrator(func(ctx context.Context, m *migrate.Migrator, args []string) error {
+				version, err := strconv.ParseInt(args[0], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid version %q", args[0])
+				}
+				return m.Goto(ctx, version)
+			}),
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show applied and pending migrations",
+			Args:  cobra.NoArgs,
+			RunE:  withMigrator(printMigrateStatus),
+		},
+	)
+
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrateCreate(cmd *cobra.Command, args []string) error {
+	files, err := migrate.Create(migrationsDir, args[0])
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Printf("Created %s\n", f)
+	}
+	return nil
+}
+
+// withMigrator 加载配置和迁移文件, 打开连接后执行 fn
+func withMigrator(fn func(ctx context.Context, m *migrate.Migrator, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		configFile, _ := cmd.Flags().GetString("config")
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+
+		migrations, err := migrate.Load(os.DirFS(migrationsDir), ".")
+		if err != nil {
+			return err
+		}
+
+		conn, err := db.Open(ctx, migrateDB)
+		if err != nil {
+			return err
+		}
+		defer db.Close(ctx)
+
+		target, ok := conn.(db.Migratable)
+		if !ok {
+			return fmt.Errorf("database %q (%T) does not support migrations", migrateDB, conn)
+		}
+		m, err := migrate.New(target.MigrationDriver(migrationTable), migrations)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, m, args)
+	}
+}
+
+// loadConfig 初始化配置管理器, 未指定文件时查找当前目录的 config.yaml
+func loadConfig(configFile string) error {
+	if configFile == "" {
+		return confManager.InitConfig(".", "config", "yaml")
+	}
+	ext := filepath.Ext(configFile)
+	name := strings.TrimSuffix(filepath.Base(configFile), ext)
+	return confManager.InitConfig(filepath.Dir(configFile), name, strings.TrimPrefix(ext, "."))
+}
+
+func printMigrateStatus(ctx context.Context, m *migrate.Migrator, args []string) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case s.Missing:
+			state = "missing"
+		case s.Modified:
+			state = "modified"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
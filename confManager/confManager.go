@@ -13,6 +13,9 @@ var (
 	once            sync.Once
 	v               *viper.Viper
 	ConfInitialized bool = false
+
+	handlersMu     sync.RWMutex
+	changeHandlers []func()
 )
 
 // InitConfig 初始化配置管理器
@@ -52,7 +55,9 @@ func InitConfig(configPath string, configName string, configType string) error {
 			// 重新读取配置文件
 			if err := v.ReadInConfig(); err != nil {
 				fmt.Printf("Error reloading config: %v\n", err)
+				return
 			}
+			notifyChange()
 		})
 	})
 
@@ -110,3 +115,32 @@ func GetStringMap(key string) map[string]interface{} {
 func GetAll() map[string]interface{} {
 	return v.AllSettings()
 }
+
+// IsSet 判断配置项是否存在
+func IsSet(key string) bool {
+	return v.IsSet(key)
+}
+
+// UnmarshalKey 将指定配置项解析到结构体
+func UnmarshalKey(key string, rawVal interface{}) error {
+	return v.UnmarshalKey(key, rawVal)
+}
+
+// OnChange 注册配置文件重新加载后的回调
+func OnChange(handler func()) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	changeHandlers = append(changeHandlers, handler)
+}
+
+// notifyChange 依次调用已注册的回调
+func notifyChange() {
+	handlersMu.RLock()
+	handlers := make([]func(), len(changeHandlers))
+	copy(handlers, changeHandlers)
+	handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
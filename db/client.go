@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+// client 是驱动的一个连接池或客户端, refs 记录正在使用它的调用
+type client[C any] struct {
+	conn  C
+	close func(ctx context.Context) error
+	refs  sync.WaitGroup
+}
+
+// clientHolder 保存驱动当前使用的客户端, 可以被多个 goroutine 同时使用.
+// 调用通过 acquire 取得客户端, 结束后调用 release. replace 原子替换客户端,
+// 旧客户端在已经取得它的调用全部结束后关闭, 进行中的调用不会看到被关闭的客户端
+type clientHolder[C any] struct {
+	mu      sync.RWMutex
+	current *client[C]
+}
+
+// acquire 返回当前客户端和释放函数, 未连接时返回 ErrNotConnected
+func (h *clientHolder[C]) acquire() (C, func(), error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	c := h.current
+	if c == nil {
+		var zero C
+		return zero, nil, ErrNotConnected
+	}
+	c.refs.Add(1)
+	return c.conn, c.refs.Done, nil
+}
+
+// connected 判断是否有可用的客户端
+func (h *clientHolder[C]) connected() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.current != nil
+}
+
+// replace 用 conn 替换当前客户端, 旧客户端在后台等待进行中的调用结束后关闭
+func (h *clientHolder[C]) replace(ctx context.Context, driver string, conn C, closeFn func(ctx context.Context) error) {
+	old := h.swap(&client[C]{conn: conn, close: closeFn})
+	if old == nil {
+		return
+	}
+	log := dbLogger(ctx, driver)
+	go func() {
+		old.refs.Wait()
+		if err := old.close(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("failed to close replaced client")
+		}
+	}()
+}
+
+// disconnect 移除当前客户端, 等待进行中的调用结束后关闭它. ctx 结束后不再等待, 直接关闭.
+// 未连接时返回 nil, 可以重复调用
+func (h *clientHolder[C]) disconnect(ctx context.Context) error {
+	old := h.swap(nil)
+	if old == nil {
+		return nil
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		old.refs.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+	return old.close(ctx)
+}
+
+func (h *clientHolder[C]) swap(c *client[C]) *client[C] {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := h.current
+	h.current = c
+	return old
+}
@@ -1 +1,14 @@
 package db
+
+import (
+	"context"
+
+	"github.com/Dankko0w0/gospike/logger"
+	"github.com/rs/zerolog"
+)
+
+// dbLogger 返回附带请求上下文字段的数据库日志记录器
+func dbLogger(ctx context.Context, driver string) zerolog.Logger {
+	l := logger.Module("db")
+	return logger.Enrich(ctx, l.With().Str("driver", driver).Logger())
+}
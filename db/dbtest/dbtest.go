@@ -0,0 +1,234 @@
+// Package dbtest 提供所有 db.DataOperation 驱动共用的一致性测试.
+//
+// 驱动测试连接真实数据库后调用 RunDataOperation:
+//
+//	func TestPostgreSQL(t *testing.T) {
+//		pg := db.NewPostgreSQL(cfg)
+//		if err := pg.Connect(ctx); err != nil {
+//			t.Skip(err)
+//		}
+//		dbtest.RunDataOperation(t, pg, "conformance")
+//	}
+//
+// SQL 数据库需要事先创建表, 见 PostgresSchema 和 SQLServerSchema.
+// 内置驱动的一致性测试在 db 包的 TestDataOperationConformance 中, 通过 GOSPIKE_TEST_*_DSN 环境变量启用
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/db"
+	"github.com/Dankko0w0/gospike/db/q"
+)
+
+// PostgresSchema 是 PostgreSQL 一致性测试表的结构, %s 为表名
+const PostgresSchema = `CREATE TABLE IF NOT EXISTS %s (id BIGINT PRIMARY KEY, name TEXT NOT NULL, age INT NOT NULL)`
+
+// SQLServerSchema 是 SQL Server 一致性测试表的结构, %s 为表名
+const SQLServerSchema = `IF OBJECT_ID('%[1]s', 'U') IS NULL CREATE TABLE %[1]s (id BIGINT PRIMARY KEY, name NVARCHAR(100) NOT NULL, age INT NOT NULL)`
+
+// Record 是一致性测试使用的记录
+type Record struct {
+	ID   int64  `db:"id,pk" bson:"id"`
+	Name string `db:"name" bson:"name"`
+	Age  int    `db:"age" bson:"age"`
+}
+
+var fixtures = []Record{
+	{ID: 1, Name: "alice", Age: 30},
+	{ID: 2, Name: "bob", Age: 17},
+	{ID: 3, Name: "carol", Age: 45},
+}
+
+// RunDataOperation 对 op 运行一致性测试. collection 中 id 为 1 到 4 的记录会被删除和重建.
+// 驱动实现 db.Counter, db.KeysetLister 或 db.Transactor 时同时测试这些接口
+func RunDataOperation(t *testing.T, op db.DataOperation, collection string) {
+	t.Helper()
+	ctx := context.Background()
+
+	reset := func(t *testing.T) {
+		t.Helper()
+		for id := int64(1); id <= 4; id++ {
+			if err := op.Delete(ctx, collection, map[string]interface{}{"id": id}); err != nil {
+				t.Fatalf("Delete(id=%d): %v", id, err)
+			}
+		}
+		for i := range fixtures {
+			if err := op.Create(ctx, collection, &fixtures[i]); err != nil {
+				t.Fatalf("Create(%+v): %v", fixtures[i], err)
+			}
+		}
+	}
+	t.Cleanup(func() {
+		for id := int64(1); id <= 4; id++ {
+			_ = op.Delete(ctx, collection, map[string]interface{}{"id": id})
+		}
+	})
+
+	t.Run("ReadMap", func(t *testing.T) {
+		reset(t)
+		var got Record
+		if err := op.Read(ctx, collection, map[string]interface{}{"id": int64(2)}, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != fixtures[1] {
+			t.Fatalf("got %+v, want %+v", got, fixtures[1])
+		}
+	})
+
+	t.Run("ReadQuery", func(t *testing.T) {
+		reset(t)
+		var got Record
+		if err := op.Read(ctx, collection, q.Eq("name", "carol"), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != fixtures[2] {
+			t.Fatalf("got %+v, want %+v", got, fixtures[2])
+		}
+	})
+
+	t.Run("ReadNotFound", func(t *testing.T) {
+		reset(t)
+		var got Record
+		err := op.Read(ctx, collection, q.Eq("id", int64(99)), &got)
+		if !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ListQuery", func(t *testing.T) {
+		reset(t)
+		var got []Record
+		query := q.Gte("age", 18).Sort("-age")
+		if err := op.List(ctx, collection, query, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := []Record{fixtures[2], fixtures[0]}
+		assertRecords(t, got, want)
+	})
+
+	t.Run("ListOrLimit", func(t *testing.T) {
+		reset(t)
+		var got []Record
+		query := q.Any(q.Eq("name", "bob"), q.Like("name", "car%")).Sort("id").Limit(1)
+		if err := op.List(ctx, collection, query, &got); err != nil {
+			t.Fatal(err)
+		}
+		assertRecords(t, got, []Record{fixtures[1]})
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		reset(t)
+		rec := Record{ID: 4, Name: "dave", Age: 21}
+		if err := op.Create(ctx, collection, &rec); err != nil {
+			t.Fatal(err)
+		}
+		var got Record
+		if err := op.Read(ctx, collection, q.Eq("id", int64(4)), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != rec {
+			t.Fatalf("got %+v, want %+v", got, rec)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		reset(t)
+		if err := op.Update(ctx, collection, q.Eq("id", int64(1)), map[string]interface{}{"age": 31}); err != nil {
+			t.Fatal(err)
+		}
+		var got Record
+		if err := op.Read(ctx, collection, q.Eq("id", int64(1)), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Age != 31 || got.Name != "alice" {
+			t.Fatalf("got %+v after update", got)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		reset(t)
+		if err := op.Delete(ctx, collection, q.Eq("id", int64(3))); err != nil {
+			t.Fatal(err)
+		}
+		var got Record
+		if err := op.Read(ctx, collection, q.Eq("id", int64(3)), &got); !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("got %v after delete, want ErrNotFound", err)
+		}
+	})
+
+	if counter, ok := op.(db.Counter); ok {
+		t.Run("Count", func(t *testing.T) {
+			reset(t)
+			n, err := counter.Count(ctx, collection, q.In("id", int64(1), int64(2), int64(3)).Lt("age", 40))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 2 {
+				t.Fatalf("got %d, want 2", n)
+			}
+		})
+	}
+
+	if lister, ok := op.(db.KeysetLister); ok {
+		t.Run("ListAfter", func(t *testing.T) {
+			reset(t)
+			var got []Record
+			if err := lister.ListAfter(ctx, collection, q.In("id", int64(1), int64(2), int64(3)), "id", int64(1), 1, &got); err != nil {
+				t.Fatal(err)
+			}
+			assertRecords(t, got, []Record{fixtures[1]})
+		})
+	}
+
+	if tx, ok := op.(db.Transactor); ok {
+		t.Run("TxRollback", func(t *testing.T) {
+			reset(t)
+			boom := errors.New("boom")
+			err := tx.WithTx(ctx, func(ctx context.Context) error {
+				if err := op.Delete(ctx, collection, q.Eq("id", int64(1))); err != nil {
+					return err
+				}
+				return boom
+			})
+			if !errors.Is(err, boom) {
+				t.Skipf("transactions unavailable: %v", err)
+			}
+			var got Record
+			if err := op.Read(ctx, collection, q.Eq("id", int64(1)), &got); err != nil {
+				t.Fatalf("record deleted despite rollback: %v", err)
+			}
+		})
+
+		t.Run("TxCommit", func(t *testing.T) {
+			reset(t)
+			err := tx.WithTx(ctx, func(ctx context.Context) error {
+				return op.Update(ctx, collection, q.Eq("id", int64(2)), map[string]interface{}{"age": 18})
+			})
+			if err != nil {
+				t.Skipf("transactions unavailable: %v", err)
+			}
+			var got Record
+			if err := op.Read(ctx, collection, q.Eq("id", int64(2)), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Age != 18 {
+				t.Fatalf("got %+v after commit", got)
+			}
+		})
+	}
+}
+
+func assertRecords(t *testing.T, got, want []Record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d records %+v, want %+v", len(got), got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("record %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// buildURL 用 Host, Port 和凭据构造连接 URI, 用户名和密码经过转义
+func (c *Config) buildURL(scheme string) *url.URL {
+	u := &url.URL{
+		Scheme: scheme,
+		Host:   c.address(),
+	}
+	if c.Username != "" || c.Password != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+	return u
+}
+
+// address 返回 host:port, IPv6 地址加方括号
+func (c *Config) address() string {
+	if c.Port == 0 {
+		return c.Host
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// withParams 把 params 合并到 URI 形式的 dsn 中, dsn 中已有的参数优先
+func withParams(dsn string, params url.Values) (string, error) {
+	if len(params) == 0 {
+		return dsn, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return "", fmt.Errorf("driver options require a URI dsn")
+	}
+	query := u.Query()
+	for k, v := range params {
+		if _, ok := query[k]; !ok {
+			query[k] = v
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+var dsnPassword = regexp.MustCompile(`(?i)((?:password|pwd)\s*=\s*)('[^']*'|[^;\s]*)`)
+
+// Redacted 返回隐藏密码的连接描述, 可以安全地写入日志.
+// URI 中的 userinfo 密码和 password, pwd 等查询参数都被隐藏
+func (c *Config) Redacted() string {
+	if c.DSN != "" {
+		if u, err := url.Parse(c.DSN); err == nil && u.Scheme != "" {
+			if u.Opaque != "" {
+				// 如 "odbc:server=db;password=secret"
+				return dsnPassword.ReplaceAllString(c.DSN, "${1}xxxxx")
+			}
+			u.RawQuery = redactQuery(u.RawQuery)
+			return u.Redacted()
+		}
+		// key=value 形式, 如 "host=db password=secret" 或 "server=db;password=secret"
+		return dsnPassword.ReplaceAllString(c.DSN, "${1}xxxxx")
+	}
+
+	u := c.buildURL("")
+	if c.Database != "" {
+		u.Path = "/" + c.Database
+	}
+	return strings.TrimPrefix(u.Redacted(), "//")
+}
+
+// redactQuery 隐藏查询参数中的密码, 其余参数保持原样和原顺序
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		key, _, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if isPasswordParam(name) {
+			parts[i] = key + "=xxxxx"
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// isPasswordParam 判断参数名是否为密码, 如 password, pwd, sslpassword
+func isPasswordParam(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return name == "pwd" || strings.HasSuffix(name, "password")
+}
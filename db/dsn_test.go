@@ -0,0 +1,55 @@
+package db
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestConfigRedacted(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"userinfo", Config{DSN: "postgres://app:secret@db:5432/orders"}, "postgres://app:xxxxx@db:5432/orders"},
+		{"query password", Config{DSN: "postgres://db/orders?sslmode=require&password=secret"},
+			"postgres://db/orders?sslmode=require&password=xxxxx"},
+		{"sqlserver query", Config{DSN: "sqlserver://db?user%20id=sa&Password=secret&database=x"},
+			"sqlserver://db?user%20id=sa&Password=xxxxx&database=x"},
+		{"pwd and sslpassword", Config{DSN: "postgres://db?pwd=a&sslpassword=b"}, "postgres://db?pwd=xxxxx&sslpassword=xxxxx"},
+		{"key value", Config{DSN: "host=db user=app password=secret dbname=orders"}, "host=db user=app password=xxxxx dbname=orders"},
+		{"quoted key value", Config{DSN: "host=db password='se cret' dbname=orders"}, "host=db password=xxxxx dbname=orders"},
+		{"semicolons", Config{DSN: "server=db;user id=sa;Pwd=secret;database=x"}, "server=db;user id=sa;Pwd=xxxxx;database=x"},
+		{"odbc", Config{DSN: "odbc:server=db;password=secret"}, "odbc:server=db;password=xxxxx"},
+		{"fields", Config{Host: "db", Port: 5432, Username: "app", Password: "secret", Database: "orders"}, "app:xxxxx@db:5432/orders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.Redacted()
+			if got != tt.want {
+				t.Errorf("Redacted() = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(got, "secret") || strings.Contains(got, "se cret") {
+				t.Errorf("Redacted() leaked the password: %q", got)
+			}
+		})
+	}
+}
+
+func TestBuildURLEscapesCredentials(t *testing.T) {
+	cfg := Config{Host: "::1", Port: 5432, Username: "us@r:x", Password: "p@ss/w:rd?#% "}
+	u := cfg.buildURL("postgres")
+
+	parsed, err := url.Parse(u.String())
+	if err != nil {
+		t.Fatalf("buildURL produced an unparsable URI %q: %v", u, err)
+	}
+	password, _ := parsed.User.Password()
+	if parsed.User.Username() != cfg.Username || password != cfg.Password {
+		t.Errorf("credentials round trip: got %q/%q", parsed.User.Username(), password)
+	}
+	if parsed.Host != "[::1]:5432" {
+		t.Errorf("host = %q, want [::1]:5432", parsed.Host)
+	}
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Dankko0w0/gospike/db/sqlbuilder"
+)
+
+// ErrNotFound 表示查询没有匹配的记录, 可用 errors.Is 判断
+var ErrNotFound = errors.New("record not found")
+
+// ErrNotConnected 表示驱动尚未连接或已经断开
+var ErrNotConnected = errors.New("database not connected")
+
+// ErrNoCondition 表示 Update 或 Delete 的 filter 为空, 会影响所有记录.
+// 需要影响所有记录时传入 q.All() (SQL 驱动也可以用 sqlbuilder.All())
+var ErrNoCondition = sqlbuilder.ErrNoCondition
+
+// NotFoundError 是 Read 在没有匹配记录时返回的错误
+type NotFoundError struct {
+	Collection string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Collection, ErrNotFound)
+}
+
+// Is 使 errors.Is(err, ErrNotFound) 成立
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
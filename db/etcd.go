@@ -3,87 +3,121 @@ package db
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 type Etcd struct {
-	config     *Config
-	client     *clientv3.Client
-	connected  bool
-	maxRetries int
+	config *Config
+	client clientHolder[*clientv3.Client]
+	retrier
 }
 
 func NewEtcd(config *Config) *Etcd {
 	return &Etcd{
-		config:     config,
-		maxRetries: 3,
+		config:  config,
+		retrier: newRetrier(etcdConnRetryable),
 	}
 }
 
 func (e *Etcd) Connect(ctx context.Context) error {
+	opts, err := e.config.ParseOptions()
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := opts.tlsConfig(e.config.Host)
+	if err != nil {
+		return err
+	}
+	dialTimeout := 5 * time.Second
+	if opts.ConnectTimeout > 0 {
+		dialTimeout = opts.ConnectTimeout
+	}
+
 	client, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{fmt.Sprintf("%s:%d", e.config.Host, e.config.Port)},
+		Endpoints:   e.endpoints(),
 		Username:    e.config.Username,
 		Password:    e.config.Password,
-		DialTimeout: 5 * time.Second,
+		DialTimeout: dialTimeout,
+		TLS:         tlsConfig,
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to connect to etcd: %v", err)
+		return fmt.Errorf("failed to connect to etcd: %w", err)
 	}
 
-	e.client = client
-	e.connected = true
+	// 已连接时替换旧客户端, 旧客户端在进行中的调用结束后关闭
+	e.client.replace(ctx, "etcd", client, func(context.Context) error {
+		if err := client.Close(); err != nil {
+			return fmt.Errorf("failed to disconnect from etcd: %v", err)
+		}
+		return nil
+	})
 	return nil
 }
 
-func (e *Etcd) Disconnect(ctx context.Context) error {
-	if e.client != nil {
-		err := e.client.Close()
-		if err != nil {
-			return fmt.Errorf("failed to disconnect from etcd: %v", err)
+// endpoints 返回以逗号分隔的 DSN 中的地址, 未配置 DSN 时使用 Host 和 Port
+func (e *Etcd) endpoints() []string {
+	if e.config.DSN == "" {
+		return []string{e.config.address()}
+	}
+	var endpoints []string
+	for _, ep := range strings.Split(e.config.DSN, ",") {
+		if ep = strings.TrimSpace(ep); ep != "" {
+			endpoints = append(endpoints, ep)
 		}
-		e.connected = false
 	}
-	return nil
+	return endpoints
+}
+
+// Disconnect 等待进行中的调用结束后关闭客户端, 可以重复调用
+func (e *Etcd) Disconnect(ctx context.Context) error {
+	return e.client.disconnect(ctx)
 }
 
 func (e *Etcd) Ping(ctx context.Context) error {
-	if e.client == nil {
-		return fmt.Errorf("database not connected")
+	client, release, err := e.client.acquire()
+	if err != nil {
+		return err
 	}
+	defer release()
 
-	_, err := e.client.Get(ctx, "ping")
+	_, err = client.Get(ctx, "ping")
 	return err
 }
 
 func (e *Etcd) IsConnected() bool {
-	return e.connected
+	return e.client.connected()
 }
 
+// Reconnect 按 RetryPolicy 建立新客户端并替换旧客户端, 见 SetRetryPolicy
 func (e *Etcd) Reconnect(ctx context.Context) error {
-	e.Disconnect(ctx)
-
-	for i := 0; i < e.maxRetries; i++ {
-		err := e.Connect(ctx)
-		if err == nil {
-			return nil
-		}
-		time.Sleep(time.Second * time.Duration(i+1))
-	}
-	return fmt.Errorf("failed to reconnect after %d attempts", e.maxRetries)
+	return reconnect(ctx, "etcd", e.RetryPolicy(), e.Connect)
 }
 
 // Etcd specific operations
 func (e *Etcd) Put(ctx context.Context, key, value string) error {
-	_, err := e.client.Put(ctx, key, value)
+	client, release, err := e.client.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = client.Put(ctx, key, value)
 	return err
 }
 
 func (e *Etcd) Get(ctx context.Context, key string) (string, error) {
-	resp, err := e.client.Get(ctx, key)
+	client, release, err := e.client.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	resp, err := client.Get(ctx, key)
 	if err != nil {
 		return "", err
 	}
@@ -94,10 +128,30 @@ func (e *Etcd) Get(ctx context.Context, key string) (string, error) {
 }
 
 func (e *Etcd) Delete(ctx context.Context, key string) error {
-	_, err := e.client.Delete(ctx, key)
+	client, release, err := e.client.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = client.Delete(ctx, key)
 	return err
 }
 
+// Watch 监听 key 的变化. 通道在 ctx 结束或客户端被 Reconnect 替换并关闭后关闭, 调用方需要重新 Watch.
+// 未连接时返回已关闭的通道
 func (e *Etcd) Watch(ctx context.Context, key string) clientv3.WatchChan {
-	return e.client.Watch(ctx, key)
+	client, release, err := e.client.acquire()
+	if err != nil {
+		ch := make(chan clientv3.WatchResponse)
+		close(ch)
+		return ch
+	}
+	defer release()
+	return client.Watch(ctx, key)
+}
+
+// etcdConnRetryable 判断连接错误是否可以重试, 认证失败不重试
+func etcdConnRetryable(err error) bool {
+	return rpctypes.Error(err) != rpctypes.ErrAuthFailed
 }
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSQL 是记录所有语句的 database/sql 驱动, 用于检查生成的 SQL 和事务流程
+type fakeSQL struct {
+	mu         sync.Mutex
+	statements []string
+	// fail 返回非 nil 时对应的语句执行失败
+	fail func(query string) error
+}
+
+// newFakeSQLServer 返回使用 fakeSQL 连接池的 SQLServer
+func newFakeSQLServer(t *testing.T) (*SQLServer, *fakeSQL) {
+	t.Helper()
+	fake := &fakeSQL{}
+	db := sql.OpenDB(fake)
+	db.SetMaxOpenConns(1)
+	s := NewSQLServer(&Config{})
+	s.db.replace(context.Background(), "sqlserver", db, func(context.Context) error { return db.Close() })
+	t.Cleanup(func() { _ = s.Disconnect(context.Background()) })
+	return s, fake
+}
+
+func (f *fakeSQL) record(stmt string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, stmt)
+	if f.fail != nil {
+		return f.fail(stmt)
+	}
+	return nil
+}
+
+// log 返回已执行的语句并清空记录
+func (f *fakeSQL) log() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := f.statements
+	f.statements = nil
+	return out
+}
+
+func (f *fakeSQL) Connect(ctx context.Context) (driver.Conn, error) { return &fakeConn{f}, nil }
+func (f *fakeSQL) Driver() driver.Driver                            { return fakeDriver{f} }
+
+type fakeDriver struct{ f *fakeSQL }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d.f}, nil }
+
+type fakeConn struct{ f *fakeSQL }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.f.record("BEGIN"); err != nil {
+		return nil, err
+	}
+	return fakeTx{c.f}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.f.record(formatStatement(query, args)); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.f.record(formatStatement(query, args)); err != nil {
+		return nil, err
+	}
+	return fakeRowsEmpty{}, nil
+}
+
+func formatStatement(query string, args []driver.NamedValue) string {
+	if len(args) == 0 {
+		return query
+	}
+	values := make([]string, len(args))
+	for i, a := range args {
+		values[i] = fmt.Sprint(a.Value)
+	}
+	return query + " [" + strings.Join(values, " ") + "]"
+}
+
+type fakeTx struct{ f *fakeSQL }
+
+func (t fakeTx) Commit() error   { return t.f.record("COMMIT") }
+func (t fakeTx) Rollback() error { return t.f.record("ROLLBACK") }
+
+type fakeRowsEmpty struct{}
+
+func (fakeRowsEmpty) Columns() []string              { return nil }
+func (fakeRowsEmpty) Close() error                   { return nil }
+func (fakeRowsEmpty) Next(dest []driver.Value) error { return io.EOF }
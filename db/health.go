@@ -0,0 +1,415 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthState 连接健康状态
+type HealthState int
+
+const (
+	// HealthUnknown 尚未检查
+	HealthUnknown HealthState = iota
+	// HealthUp Ping 成功且延迟正常
+	HealthUp
+	// HealthDegraded Ping 延迟超过阈值, 或连续失败次数未达到 FailureThreshold
+	HealthDegraded
+	// HealthDown 连续失败次数达到 FailureThreshold
+	HealthDown
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthUp:
+		return "up"
+	case HealthDegraded:
+		return "degraded"
+	case HealthDown:
+		return "down"
+	}
+	return "unknown"
+}
+
+// MarshalText 使状态在 JSON 中显示为字符串
+func (s HealthState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// HealthStatus 是单个连接最近一次检查的结果
+type HealthStatus struct {
+	Name      string        `json:"name"`
+	State     HealthState   `json:"state"`
+	Latency   time.Duration `json:"latency"`
+	CheckedAt time.Time     `json:"checkedAt"`
+	// Since 是进入当前状态的时间
+	Since    time.Time `json:"since"`
+	Failures int       `json:"failures"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// HealthEvent 在连接状态变化时发出
+type HealthEvent struct {
+	Name   string
+	From   HealthState
+	To     HealthState
+	Status HealthStatus
+}
+
+// HealthOption 修改 HealthMonitor 配置
+type HealthOption func(*HealthMonitor)
+
+// HealthInterval 设置检查间隔, 默认 15s
+func HealthInterval(d time.Duration) HealthOption {
+	return func(h *HealthMonitor) { h.interval = d }
+}
+
+// HealthTimeout 设置单次 Ping 的超时, 默认 5s
+func HealthTimeout(d time.Duration) HealthOption {
+	return func(h *HealthMonitor) { h.timeout = d }
+}
+
+// DegradedLatency 设置 Ping 延迟超过多少时视为 degraded, 0 表示不按延迟判断
+func DegradedLatency(d time.Duration) HealthOption {
+	return func(h *HealthMonitor) { h.degradedLatency = d }
+}
+
+// FailureThreshold 设置连续失败多少次视为 down, 默认 3
+func FailureThreshold(n int) HealthOption {
+	return func(h *HealthMonitor) { h.failureThreshold = n }
+}
+
+// AutoReconnect 设置 down 时是否调用 Reconnect, 默认开启
+func AutoReconnect(enabled bool) HealthOption {
+	return func(h *HealthMonitor) { h.reconnect = enabled }
+}
+
+// HealthMonitor 定期 Ping 注册的连接, 跟踪状态变化并在 down 时自动重连.
+// 连接的 IsConnected 只表示客户端已建立且未断开, 不反映数据库是否可达, 可达性以 Status 为准
+type HealthMonitor struct {
+	interval         time.Duration
+	timeout          time.Duration
+	degradedLatency  time.Duration
+	failureThreshold int
+	reconnect        bool
+
+	mu         sync.Mutex
+	conns      map[string]DBInterface
+	registries []*Registry
+	status     map[string]HealthStatus
+	checking   map[string]bool
+	handlers   []func(HealthEvent)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewHealthMonitor 创建 HealthMonitor, 需要调用 Start 开始后台检查
+func NewHealthMonitor(opts ...HealthOption) *HealthMonitor {
+	h := &HealthMonitor{
+		interval:         15 * time.Second,
+		timeout:          5 * time.Second,
+		failureThreshold: 3,
+		reconnect:        true,
+		conns:            make(map[string]DBInterface),
+		status:           make(map[string]HealthStatus),
+		checking:         make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.failureThreshold < 1 {
+		h.failureThreshold = 1
+	}
+	return h
+}
+
+// Register 添加要监控的连接, 同名连接被替换
+func (h *HealthMonitor) Register(name string, conn DBInterface) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[name] = conn
+}
+
+// Unregister 停止监控名为 name 的连接
+func (h *HealthMonitor) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, name)
+	delete(h.status, name)
+}
+
+// Watch 监控 Registry 中所有已打开的连接, 包括之后打开的连接
+func (h *HealthMonitor) Watch(r *Registry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.registries = append(h.registries, r)
+}
+
+// OnChange 注册状态变化回调, 回调在检查的 goroutine 中同步执行
+func (h *HealthMonitor) OnChange(fn func(HealthEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers = append(h.handlers, fn)
+}
+
+// Start 在后台开始定期检查, 立即执行第一次检查. 重复调用无效
+func (h *HealthMonitor) Start(ctx context.Context) {
+	h.mu.Lock()
+	if h.cancel != nil {
+		h.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	h.cancel, h.done = cancel, done
+	h.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			h.Check(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台检查并等待正在进行的检查结束
+func (h *HealthMonitor) Stop() {
+	h.mu.Lock()
+	cancel, done := h.cancel, h.done
+	h.cancel, h.done = nil, nil
+	h.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Check 并发检查所有连接一次并等待完成, 上一次检查仍在进行的连接被跳过.
+// 已经不在监控范围内的连接 (如 Registry 已关闭) 的状态被删除
+func (h *HealthMonitor) Check(ctx context.Context) {
+	targets := h.targets()
+	h.mu.Lock()
+	for name := range h.status {
+		if _, ok := targets[name]; !ok {
+			delete(h.status, name)
+		}
+	}
+	h.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for name, conn := range targets {
+		h.mu.Lock()
+		busy := h.checking[name]
+		h.checking[name] = true
+		h.mu.Unlock()
+		if busy {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, conn DBInterface) {
+			defer wg.Done()
+			h.check(ctx, name, conn)
+
+			h.mu.Lock()
+			delete(h.checking, name)
+			h.mu.Unlock()
+		}(name, conn)
+	}
+	wg.Wait()
+}
+
+// targets 返回直接注册和 Registry 中的连接, 直接注册的优先
+func (h *HealthMonitor) targets() map[string]DBInterface {
+	h.mu.Lock()
+	registries := append([]*Registry(nil), h.registries...)
+	targets := make(map[string]DBInterface, len(h.conns))
+	for name, conn := range h.conns {
+		targets[name] = conn
+	}
+	h.mu.Unlock()
+
+	for _, r := range registries {
+		for _, name := range r.Names() {
+			if _, ok := targets[name]; ok {
+				continue
+			}
+			if conn, ok := r.Get(name); ok {
+				targets[name] = conn
+			}
+		}
+	}
+	return targets
+}
+
+func (h *HealthMonitor) check(ctx context.Context, name string, conn DBInterface) {
+	latency, err := h.ping(ctx, conn)
+
+	h.mu.Lock()
+	prev := h.status[name]
+	h.mu.Unlock()
+
+	status := HealthStatus{Name: name, Latency: latency, CheckedAt: time.Now()}
+	if err != nil {
+		status.Failures = prev.Failures + 1
+		status.Error = err.Error()
+		status.State = HealthDegraded
+		if status.Failures >= h.failureThreshold {
+			status.State = HealthDown
+		}
+	} else {
+		status.State = HealthUp
+		if h.degradedLatency > 0 && latency > h.degradedLatency {
+			status.State = HealthDegraded
+		}
+	}
+	h.update(ctx, prev, status)
+
+	if status.State == HealthDown && h.reconnect && ctx.Err() == nil {
+		log := dbLogger(ctx, "health")
+		log.Info().Str("name", name).Msg("reconnecting unhealthy database")
+		if err := conn.Reconnect(ctx); err != nil {
+			log.Warn().Err(err).Str("name", name).Msg("health monitor reconnect failed")
+			return
+		}
+		// 重连成功后立即复查
+		latency, err := h.ping(ctx, conn)
+		status = HealthStatus{Name: name, State: HealthUp, Latency: latency, CheckedAt: time.Now()}
+		if err != nil {
+			status.State = HealthDown
+			status.Failures = h.failureThreshold
+			status.Error = err.Error()
+		}
+		h.update(ctx, h.Status()[name], status)
+	}
+}
+
+func (h *HealthMonitor) ping(ctx context.Context, conn DBInterface) (time.Duration, error) {
+	pingCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := conn.Ping(pingCtx)
+	return time.Since(start), err
+}
+
+// update 保存状态, 状态变化时记录日志并调用回调
+func (h *HealthMonitor) update(ctx context.Context, prev, status HealthStatus) {
+	status.Since = prev.Since
+	changed := prev.State != status.State
+	if changed || status.Since.IsZero() {
+		status.Since = status.CheckedAt
+	}
+
+	h.mu.Lock()
+	if _, ok := h.conns[status.Name]; !ok && !h.watched(status.Name) {
+		// 检查期间被取消注册
+		h.mu.Unlock()
+		return
+	}
+	h.status[status.Name] = status
+	handlers := append(([]func(HealthEvent))(nil), h.handlers...)
+	h.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	log := dbLogger(ctx, "health")
+	event := log.Info()
+	if status.State == HealthDown || status.State == HealthDegraded {
+		event = log.Warn()
+	}
+	event.Str("name", status.Name).
+		Stringer("from", prev.State).
+		Stringer("to", status.State).
+		Dur("latency", status.Latency).
+		Str("error", status.Error).
+		Msg("database health changed")
+
+	e := HealthEvent{Name: status.Name, From: prev.State, To: status.State, Status: status}
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
+
+// watched 判断连接是否来自被监控的 Registry, 调用时需持有 h.mu
+func (h *HealthMonitor) watched(name string) bool {
+	for _, r := range h.registries {
+		if _, ok := r.Get(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Status 返回所有连接最近一次检查的结果
+func (h *HealthMonitor) Status() map[string]HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := make(map[string]HealthStatus, len(h.status))
+	for name, s := range h.status {
+		status[name] = s
+	}
+	return status
+}
+
+// Healthy 判断是否没有处于 down 状态的连接
+func (h *HealthMonitor) Healthy() bool {
+	for _, s := range h.Status() {
+		if s.State == HealthDown {
+			return false
+		}
+	}
+	return true
+}
+
+// Handler 返回 /healthz 处理器, 所有连接不为 down 时返回 200, 否则返回 503
+func (h *HealthMonitor) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := h.Status()
+		names := make([]string, 0, len(status))
+		for name := range status {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		body := struct {
+			Status    string         `json:"status"`
+			Databases []HealthStatus `json:"databases"`
+		}{Status: "ok", Databases: make([]HealthStatus, 0, len(names))}
+		code := http.StatusOK
+		for _, name := range names {
+			s := status[name]
+			body.Databases = append(body.Databases, s)
+			if s.State == HealthDown {
+				code = http.StatusServiceUnavailable
+				body.Status = "unavailable"
+			} else if s.State == HealthDegraded && body.Status == "ok" {
+				body.Status = "degraded"
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			log := dbLogger(r.Context(), "health")
+			log.Warn().Err(fmt.Errorf("failed to write health response: %w", err)).Send()
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// pingConn is a DBInterface whose Ping returns err
+type pingConn struct {
+	err error
+}
+
+func (c *pingConn) Connect(ctx context.Context) error    { return nil }
+func (c *pingConn) Disconnect(ctx context.Context) error { return nil }
+func (c *pingConn) Ping(ctx context.Context) error       { return c.err }
+func (c *pingConn) IsConnected() bool                    { return true }
+func (c *pingConn) Reconnect(ctx context.Context) error  { return c.err }
+
+func TestHealthMonitorPrunesClosedRegistryConnections(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(DefaultConfigKey)
+	if err := r.Add("primary", &pingConn{err: errors.New("unreachable")}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHealthMonitor(FailureThreshold(1), AutoReconnect(false))
+	h.Watch(r)
+	h.Check(ctx)
+	if h.Healthy() {
+		t.Fatal("Healthy() = true with a down connection")
+	}
+
+	if err := r.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	h.Check(ctx)
+	if status := h.Status(); len(status) != 0 {
+		t.Errorf("Status() = %v, want no entries after the registry closed", status)
+	}
+	if !h.Healthy() {
+		t.Error("Healthy() = false after the down connection left the registry")
+	}
+}
+
+func TestHealthMonitorRecovers(t *testing.T) {
+	ctx := context.Background()
+	conn := &pingConn{err: errors.New("unreachable")}
+	h := NewHealthMonitor(FailureThreshold(2), AutoReconnect(false))
+	h.Register("primary", conn)
+
+	var events []HealthEvent
+	h.OnChange(func(e HealthEvent) { events = append(events, e) })
+
+	h.Check(ctx)
+	if got := h.Status()["primary"].State; got != HealthDegraded {
+		t.Fatalf("after one failure: %v, want degraded", got)
+	}
+	h.Check(ctx)
+	if got := h.Status()["primary"].State; got != HealthDown {
+		t.Fatalf("after two failures: %v, want down", got)
+	}
+	conn.err = nil
+	h.Check(ctx)
+	if got := h.Status()["primary"].State; got != HealthUp {
+		t.Fatalf("after recovery: %v, want up", got)
+	}
+	if len(events) != 3 {
+		t.Errorf("got %d change events, want 3", len(events))
+	}
+}
@@ -5,7 +5,9 @@ import (
 )
 
 // Config 数据库配置结构
+// DSN 不为空时直接作为连接 URI 使用, 忽略 Host, Port, Username, Password 和 Database
 type Config struct {
+	DSN      string
 	Host     string
 	Port     int
 	Username string
@@ -19,6 +21,7 @@ type DBInterface interface {
 	Connect(ctx context.Context) error
 	Disconnect(ctx context.Context) error
 	Ping(ctx context.Context) error
+	// IsConnected 表示客户端已建立且未断开, 不检查数据库是否可达; 需要时使用 Ping 或 HealthMonitor
 	IsConnected() bool
 	Reconnect(ctx context.Context) error
 }
@@ -31,3 +34,40 @@ type DataOperation interface {
 	Delete(ctx context.Context, collection string, filter interface{}) error
 	List(ctx context.Context, collection string, filter interface{}, results interface{}) error
 }
+
+// Counter 由可以直接统计记录数的驱动实现, filter 与 List 相同
+type Counter interface {
+	Count(ctx context.Context, collection string, filter interface{}) (int64, error)
+}
+
+// KeysetLister 由支持键集分页的驱动实现.
+// 返回满足 filter 且 key 大于 after 的记录, 按 key 升序, 最多 limit 条; after 为 nil 时从头开始
+type KeysetLister interface {
+	ListAfter(ctx context.Context, collection string, filter interface{}, key string, after interface{}, limit int, results interface{}) error
+}
+
+// 编译期检查驱动实现的接口
+var (
+	_ DBInterface = (*PostgreSQL)(nil)
+	_ DBInterface = (*SQLServer)(nil)
+	_ DBInterface = (*MongoDB)(nil)
+	_ DBInterface = (*Redis)(nil)
+	_ DBInterface = (*Etcd)(nil)
+
+	_ DataOperation = (*PostgreSQL)(nil)
+	_ DataOperation = (*SQLServer)(nil)
+	_ DataOperation = (*MongoDB)(nil)
+
+	_ Counter      = (*PostgreSQL)(nil)
+	_ Counter      = (*SQLServer)(nil)
+	_ Counter      = (*MongoDB)(nil)
+	_ KeysetLister = (*PostgreSQL)(nil)
+	_ KeysetLister = (*SQLServer)(nil)
+	_ KeysetLister = (*MongoDB)(nil)
+	_ Transactor   = (*PostgreSQL)(nil)
+	_ Transactor   = (*SQLServer)(nil)
+	_ Transactor   = (*MongoDB)(nil)
+	_ Migratable   = (*PostgreSQL)(nil)
+	_ Migratable   = (*SQLServer)(nil)
+	_ Migratable   = (*MongoDB)(nil)
+)
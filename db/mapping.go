@@ -0,0 +1,217 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// fieldInfo 描述结构体字段与列的对应关系
+type fieldInfo struct {
+	column    string
+	index     []int
+	omitEmpty bool
+}
+
+// structInfo 是结构体的列映射, 字段顺序与声明顺序一致
+type structInfo struct {
+	fields   []fieldInfo
+	byColumn map[string]int // 小写列名 -> fields 下标
+}
+
+var structCache sync.Map // reflect.Type -> *structInfo
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType = reflect.TypeOf((*interface{ Scan(src any) error })(nil)).Elem()
+)
+
+// getStructInfo 解析结构体的 db 标签. 没有标签的字段使用 snake_case 名称,
+// db:"-" 忽略字段, db:"name,omitempty" 在写入时忽略零值,
+// 没有标签的嵌入结构体字段会展开到外层
+func getStructInfo(t reflect.Type) *structInfo {
+	if info, ok := structCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+
+	info := &structInfo{byColumn: make(map[string]int)}
+	collectFields(t, nil, info)
+	structCache.Store(t, info)
+	return info
+}
+
+func collectFields(t reflect.Type, parent []int, info *structInfo) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+
+		index := append(append([]int{}, parent...), i)
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && !hasTag && ft.Kind() == reflect.Struct && !isLeafType(ft) {
+			collectFields(ft, index, info)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = toSnakeCase(f.Name)
+		}
+		key := strings.ToLower(name)
+		if _, dup := info.byColumn[key]; dup {
+			// 外层字段优先于嵌入字段
+			continue
+		}
+		info.byColumn[key] = len(info.fields)
+		info.fields = append(info.fields, fieldInfo{
+			column:    name,
+			index:     index,
+			omitEmpty: strings.Contains(opts, "omitempty"),
+		})
+	}
+}
+
+// isLeafType 判断结构体类型是否作为单个列值处理
+func isLeafType(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	return t.Implements(valuerType) || reflect.PointerTo(t).Implements(scannerType)
+}
+
+// isStructTarget 判断类型是否需要按列映射到字段
+func isStructTarget(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !isLeafType(t)
+}
+
+// toSnakeCase 将 UserID 转为 user_id, HTTPServer 转为 http_server
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// fieldByIndex 按索引路径取字段, 为 nil 的嵌入指针分配内存
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndexNoAlloc 按索引路径取字段, 遇到 nil 嵌入指针时返回 false
+func fieldByIndexNoAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// columnMode 决定结构体字段在生成列时如何处理零值
+type columnMode int
+
+const (
+	// modeWrite 用于 Create/Update, 跳过 omitempty 的零值字段
+	modeWrite columnMode = iota
+	// modeFilter 用于查询条件, 跳过所有零值字段
+	modeFilter
+)
+
+// columnSet 是已经转换好的列名和值, columnValues 原样返回, 保持列的顺序
+type columnSet struct {
+	columns []string
+	values  []interface{}
+}
+
+// columnValues 将 map 或结构体转换为列名和值. map 按键排序, 结构体按字段声明顺序
+func columnValues(data interface{}, mode columnMode) ([]string, []interface{}, error) {
+	if data == nil {
+		return nil, nil, nil
+	}
+	if set, ok := data.(columnSet); ok {
+		return set.columns, set.values, nil
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil, nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, nil, fmt.Errorf("unsupported map key type %s", v.Type().Key())
+		}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())).Interface()
+		}
+		return keys, values, nil
+
+	case isStructTarget(v.Type()):
+		info := getStructInfo(v.Type())
+		columns := make([]string, 0, len(info.fields))
+		values := make([]interface{}, 0, len(info.fields))
+		for _, f := range info.fields {
+			fv, ok := fieldByIndexNoAlloc(v, f.index)
+			if !ok {
+				continue
+			}
+			if fv.IsZero() && (mode == modeFilter || f.omitEmpty) {
+				continue
+			}
+			columns = append(columns, f.column)
+			values = append(values, fv.Interface())
+		}
+		return columns, values, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported data type %T, expected a map or struct", data)
+}
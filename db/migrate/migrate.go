@@ -0,0 +1,348 @@
+// Package migrate 实现版本化的数据库迁移.
+// 迁移文件命名为 <version>_<name>.up.sql 和 <version>_<name>.down.sql,
+// 可以来自磁盘目录 (os.DirFS) 或 embed.FS. 执行迁移的 Driver 由 db 包中的驱动提供
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Dankko0w0/gospike/logger"
+)
+
+// DefaultTable 是记录已执行迁移的默认表名
+const DefaultTable = "schema_migrations"
+
+// Direction 迁移方向
+type Direction int
+
+const (
+	Up Direction = iota
+	Down
+)
+
+func (d Direction) String() string {
+	if d == Down {
+		return "down"
+	}
+	return "up"
+}
+
+var (
+	// ErrChecksumMismatch 表示已执行的迁移文件在执行后被修改
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrMissing 表示数据库中记录的迁移在源中不存在
+	ErrMissing = errors.New("applied migration is missing from source")
+	// ErrIrreversible 表示迁移没有 down 部分
+	ErrIrreversible = errors.New("migration has no down step")
+)
+
+// Migration 是一个版本的迁移. SQL 驱动执行 UpSQL/DownSQL, MongoDB 执行 UpFunc/DownFunc
+type Migration struct {
+	Version int64
+	Name    string
+	UpSQL   string
+	DownSQL string
+
+	UpFunc   func(ctx context.Context) error
+	DownFunc func(ctx context.Context) error
+}
+
+// Checksum 返回 up 和 down 部分的 SHA-256, 修改任一部分都会被 ErrChecksumMismatch 发现.
+// 没有 down 部分时与只计算 up 的旧版本相同, Go 函数迁移返回空字符串
+func (m *Migration) Checksum() string {
+	if m.UpSQL == "" {
+		return ""
+	}
+	h := sha256.New()
+	h.Write([]byte(m.UpSQL))
+	if m.DownSQL != "" {
+		h.Write([]byte{0})
+		h.Write([]byte(m.DownSQL))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// matches 判断记录的校验和是否与迁移一致.
+// 旧版本记录的是只包含 up 部分的校验和, 这类记录只校验 up 部分
+func (m *Migration) matches(checksum string) bool {
+	if checksum == m.Checksum() {
+		return true
+	}
+	if m.DownSQL == "" {
+		return false
+	}
+	up := sha256.Sum256([]byte(m.UpSQL))
+	return checksum == hex.EncodeToString(up[:])
+}
+
+func (m *Migration) reversible() bool {
+	return m.DownSQL != "" || m.DownFunc != nil
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Record 是数据库中一条已执行迁移的记录
+type Record struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Driver 由各数据库实现, 负责加锁, 读取记录和在事务中执行迁移
+type Driver interface {
+	// Lock 获取全局迁移锁, 阻塞直到获得或 ctx 结束
+	Lock(ctx context.Context) error
+	// Unlock 释放 Lock 获取的锁
+	Unlock(ctx context.Context) error
+	// Applied 返回按版本升序排列的已执行记录, 必要时创建记录表
+	Applied(ctx context.Context) ([]Record, error)
+	// Apply 执行迁移并在同一事务中写入或删除记录
+	Apply(ctx context.Context, m *Migration, dir Direction) error
+}
+
+// Status 是单个迁移的状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified 表示已执行的迁移在源中的校验和与记录不一致
+	Modified bool
+	// Missing 表示迁移已执行但源中不存在
+	Missing bool
+}
+
+// Migrator 按版本顺序执行迁移
+type Migrator struct {
+	driver     Driver
+	migrations []*Migration
+}
+
+// New 创建 Migrator, 版本重复时返回错误
+func New(driver Driver, migrations []*Migration) (*Migrator, error) {
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", sorted[i].Version)
+		}
+	}
+	return &Migrator{driver: driver, migrations: sorted}, nil
+}
+
+// Migrations 返回按版本排序的迁移
+func (m *Migrator) Migrations() []*Migration {
+	return m.migrations
+}
+
+// Up 执行所有未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(applied map[int64]Record) error {
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, mg, Up); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 回滚最近执行的 steps 个迁移, steps <= 0 时回滚全部
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(applied map[int64]Record) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, mg, Down); err != nil {
+				return err
+			}
+			if steps--; steps == 0 {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Goto 迁移到指定版本: 执行不超过 version 的未执行迁移, 回滚大于 version 的已执行迁移
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.locked(ctx, func(applied map[int64]Record) error {
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok || mg.Version > version {
+				continue
+			}
+			if err := m.apply(ctx, mg, Up); err != nil {
+				return err
+			}
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok || mg.Version <= version {
+				continue
+			}
+			if err := m.apply(ctx, mg, Down); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Version 返回已执行的最大版本, 没有执行过迁移时返回 0
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	records, err := m.driver.Applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	return records[len(records)-1].Version, nil
+}
+
+// Status 返回所有迁移的状态, 包括源中缺失的已执行迁移
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	records, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		s := Status{Version: mg.Version, Name: mg.Name}
+		if r, ok := applied[mg.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = r.AppliedAt
+			s.Modified = !mg.matches(r.Checksum)
+			delete(applied, mg.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, r := range applied {
+		statuses = append(statuses, Status{
+			Version:   r.Version,
+			Name:      r.Name,
+			Applied:   true,
+			AppliedAt: r.AppliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// locked 在迁移锁内读取记录, 校验后执行 fn
+func (m *Migrator) locked(ctx context.Context, fn func(applied map[int64]Record) error) (err error) {
+	if err := m.driver.Lock(ctx); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if unlockErr := m.driver.Unlock(ctx); unlockErr != nil && err == nil {
+			err = fmt.Errorf("failed to release migration lock: %w", unlockErr)
+		}
+	}()
+
+	records, err := m.driver.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	applied := make(map[int64]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	if err := m.verify(records); err != nil {
+		return err
+	}
+	return fn(applied)
+}
+
+// verify 检查已执行的迁移在源中存在且未被修改
+func (m *Migrator) verify(records []Record) error {
+	var errs []error
+	for _, r := range records {
+		mg := m.find(r.Version)
+		switch {
+		case mg == nil:
+			errs = append(errs, fmt.Errorf("%w: %d_%s", ErrMissing, r.Version, r.Name))
+		case !mg.matches(r.Checksum):
+			errs = append(errs, fmt.Errorf("%w: %s", ErrChecksumMismatch, mg))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version >= version })
+	if i < len(m.migrations) && m.migrations[i].Version == version {
+		return m.migrations[i]
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, mg *Migration, dir Direction) error {
+	if dir == Down && !mg.reversible() {
+		return fmt.Errorf("%w: %s", ErrIrreversible, mg)
+	}
+
+	start := time.Now()
+	if err := m.driver.Apply(ctx, mg, dir); err != nil {
+		return fmt.Errorf("migration %s %s failed: %w", mg, dir, err)
+	}
+
+	log := logger.Module("migrate")
+	log = logger.Enrich(ctx, log)
+	log.Info().
+		Int64("version", mg.Version).
+		Str("name", mg.Name).
+		Str("direction", dir.String()).
+		Dur("elapsed", time.Since(start)).
+		Msg("migration applied")
+	return nil
+}
+
+// SplitStatements 按单独成行的 GO 分隔 SQL Server 批处理, 忽略空批次
+func SplitStatements(script string) []string {
+	var (
+		batches []string
+		current strings.Builder
+	)
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			batches = append(batches, s)
+		}
+		current.Reset()
+	}
+	for _, line := range strings.Split(script, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), "GO") {
+			flush()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	flush()
+	return batches
+}
@@ -0,0 +1,466 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// memDriver 是内存中的 Driver, 记录执行顺序
+type memDriver struct {
+	records map[int64]Record
+	calls   []string
+	locked  bool
+	fail    map[int64]error
+}
+
+func newMemDriver() *memDriver {
+	return &memDriver{records: make(map[int64]Record)}
+}
+
+func (d *memDriver) Lock(ctx context.Context) error {
+	if d.locked {
+		return errors.New("already locked")
+	}
+	d.locked = true
+	return nil
+}
+
+func (d *memDriver) Unlock(ctx context.Context) error {
+	if !d.locked {
+		return errors.New("not locked")
+	}
+	d.locked = false
+	return nil
+}
+
+func (d *memDriver) Applied(ctx context.Context) ([]Record, error) {
+	records := make([]Record, 0, len(d.records))
+	for _, r := range d.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+	return records, nil
+}
+
+func (d *memDriver) Apply(ctx context.Context, m *Migration, dir Direction) error {
+	if err := d.fail[m.Version]; err != nil {
+		return err
+	}
+	d.calls = append(d.calls, m.String()+" "+dir.String())
+	if dir == Up {
+		d.records[m.Version] = Record{Version: m.Version, Name: m.Name, Checksum: m.Checksum(), AppliedAt: time.Now()}
+	} else {
+		delete(d.records, m.Version)
+	}
+	return nil
+}
+
+func (d *memDriver) versions() []int64 {
+	var versions []int64
+	for v := range d.records {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+func testMigrations() []*Migration {
+	return []*Migration{
+		{Version: 3, Name: "add_index", UpSQL: "CREATE INDEX", DownSQL: "DROP INDEX"},
+		{Version: 1, Name: "create_users", UpSQL: "CREATE TABLE users", DownSQL: "DROP TABLE users"},
+		{Version: 2, Name: "add_email", UpSQL: "ALTER TABLE users ADD email", DownSQL: "ALTER TABLE users DROP email"},
+	}
+}
+
+func newTestMigrator(t *testing.T, migrations []*Migration) (*Migrator, *memDriver) {
+	t.Helper()
+	d := newMemDriver()
+	m, err := New(d, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, d
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users")},
+		"migrations/1_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"migrations/2_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD email")},
+		"migrations/README.md":               {Data: []byte("ignored")},
+		"migrations/3_bad name.up.sql":       {Data: []byte("ignored")},
+		"migrations/4_no_direction.sql":      {Data: []byte("ignored")},
+		"migrations/5_dir.up.sql/x":          {Data: []byte("ignored")},
+	}
+	migrations, err := Load(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	want := []*Migration{
+		{Version: 1, Name: "create_users", UpSQL: "CREATE TABLE users", DownSQL: "DROP TABLE users"},
+		{Version: 2, Name: "add_email", UpSQL: "ALTER TABLE users ADD email"},
+	}
+	if !reflect.DeepEqual(migrations, want) {
+		t.Fatalf("Load() = %v, want %v", migrations, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "conflicting names",
+			fsys: fstest.MapFS{
+				"m/1_a.up.sql":   {Data: []byte("A")},
+				"m/1_b.down.sql": {Data: []byte("B")},
+			},
+			want: "conflicting names",
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{"m/1_a.down.sql": {Data: []byte("A")}},
+			want: "has no up file",
+		},
+		{
+			name: "version overflow",
+			fsys: fstest.MapFS{"m/99999999999999999999_a.up.sql": {Data: []byte("A")}},
+			want: "invalid migration version",
+		},
+		{
+			name: "missing directory",
+			fsys: fstest.MapFS{},
+			want: "failed to read migrations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys, "m")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := "CREATE TABLE a (id INT)\nGO\n\n  go  \nCREATE INDEX ix ON a (id)\r\nGO\nSELECT 'GO'\nGOTO label\n"
+	want := []string{
+		"CREATE TABLE a (id INT)",
+		"CREATE INDEX ix ON a (id)",
+		"SELECT 'GO'\nGOTO label",
+	}
+	if got := SplitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SplitStatements() = %q, want %q", got, want)
+	}
+	if got := SplitStatements("\n GO \n"); got != nil {
+		t.Fatalf("SplitStatements() = %q, want nil", got)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	files, err := Create(dir, "create_users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Create() = %v, want up and down files", files)
+	}
+	for i, d := range []string{"up", "down"} {
+		if !strings.HasSuffix(files[i], "_create_users."+d+".sql") {
+			t.Fatalf("file %d = %s, want %s file", i, files[i], d)
+		}
+		content, err := os.ReadFile(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "-- create_users " + d + "\n"; string(content) != want {
+			t.Fatalf("%s content = %q, want %q", d, content, want)
+		}
+	}
+
+	migrations, err := Load(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 || migrations[0].Name != "create_users" || migrations[0].Version < 20000101000000 {
+		t.Fatalf("Load() = %v, want the created migration", migrations)
+	}
+
+	if _, err := Create(dir, "bad name"); err == nil {
+		t.Fatal("Create() with an invalid name succeeded")
+	}
+}
+
+func TestNewRejectsDuplicateVersions(t *testing.T) {
+	_, err := New(newMemDriver(), []*Migration{
+		{Version: 1, Name: "a", UpSQL: "A"},
+		{Version: 1, Name: "b", UpSQL: "B"},
+	})
+	if err == nil {
+		t.Fatal("New() with duplicate versions succeeded")
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	m, d := newTestMigrator(t, testMigrations())
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1_create_users up", "2_add_email up", "3_add_index up"}
+	if !reflect.DeepEqual(d.calls, want) {
+		t.Fatalf("Up() applied %q, want %q", d.calls, want)
+	}
+	if v, err := m.Version(ctx); err != nil || v != 3 {
+		t.Fatalf("Version() = %d, %v, want 3", v, err)
+	}
+
+	// 再次执行不应重复执行
+	d.calls = nil
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.calls) != 0 {
+		t.Fatalf("second Up() applied %q", d.calls)
+	}
+
+	if err := m.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"3_add_index down", "2_add_email down"}
+	if !reflect.DeepEqual(d.calls, want) {
+		t.Fatalf("Down(2) applied %q, want %q", d.calls, want)
+	}
+	if got := d.versions(); !reflect.DeepEqual(got, []int64{1}) {
+		t.Fatalf("applied versions = %v, want [1]", got)
+	}
+
+	d.calls = nil
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := m.Version(ctx); err != nil || v != 0 {
+		t.Fatalf("Version() after Down(0) = %d, %v, want 0", v, err)
+	}
+	if d.locked {
+		t.Fatal("migration lock was not released")
+	}
+}
+
+func TestGoto(t *testing.T) {
+	ctx := context.Background()
+	m, d := newTestMigrator(t, testMigrations())
+
+	if err := m.Goto(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.versions(); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Fatalf("Goto(2) applied versions = %v, want [1 2]", got)
+	}
+
+	if err := m.Goto(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	d.calls = nil
+	if err := m.Goto(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"3_add_index down", "2_add_email down"}
+	if !reflect.DeepEqual(d.calls, want) {
+		t.Fatalf("Goto(1) applied %q, want %q", d.calls, want)
+	}
+
+	if err := m.Goto(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.versions(); len(got) != 0 {
+		t.Fatalf("Goto(0) left versions %v", got)
+	}
+
+	if err := m.Goto(ctx, 42); err == nil || !strings.Contains(err.Error(), "unknown migration version 42") {
+		t.Fatalf("Goto(42) error = %v, want unknown version", err)
+	}
+}
+
+func TestDownIrreversible(t *testing.T) {
+	ctx := context.Background()
+	m, d := newTestMigrator(t, []*Migration{
+		{Version: 1, Name: "a", UpSQL: "A", DownSQL: "-A"},
+		{Version: 2, Name: "b", UpSQL: "B"},
+	})
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(ctx, 0); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("Down() error = %v, want ErrIrreversible", err)
+	}
+	if got := d.versions(); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Fatalf("applied versions = %v, want [1 2]", got)
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	ctx := context.Background()
+	m, d := newTestMigrator(t, testMigrations())
+	d.fail = map[int64]error{2: errors.New("syntax error")}
+
+	err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "migration 2_add_email up failed: syntax error") {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if got := d.versions(); !reflect.DeepEqual(got, []int64{1}) {
+		t.Fatalf("applied versions = %v, want [1]", got)
+	}
+	if d.locked {
+		t.Fatal("migration lock was not released")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(migrations []*Migration) []*Migration
+		want   error
+		status Status
+	}{
+		{
+			name: "up modified",
+			change: func(migrations []*Migration) []*Migration {
+				migrations[1].UpSQL += " -- edited"
+				return migrations
+			},
+			want:   ErrChecksumMismatch,
+			status: Status{Version: 1, Name: "create_users", Applied: true, Modified: true},
+		},
+		{
+			name: "down modified",
+			change: func(migrations []*Migration) []*Migration {
+				migrations[1].DownSQL += " -- edited"
+				return migrations
+			},
+			want:   ErrChecksumMismatch,
+			status: Status{Version: 1, Name: "create_users", Applied: true, Modified: true},
+		},
+		{
+			name: "missing",
+			change: func(migrations []*Migration) []*Migration {
+				return append(migrations[:1], migrations[2:]...)
+			},
+			want:   ErrMissing,
+			status: Status{Version: 1, Name: "create_users", Applied: true, Missing: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m, d := newTestMigrator(t, testMigrations())
+			if err := m.Up(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			changed, err := New(d, tt.change(testMigrations()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.calls = nil
+			for _, run := range []func() error{
+				func() error { return changed.Up(ctx) },
+				func() error { return changed.Down(ctx, 1) },
+				func() error { return changed.Goto(ctx, 2) },
+			} {
+				if err := run(); !errors.Is(err, tt.want) {
+					t.Fatalf("error = %v, want %v", err, tt.want)
+				}
+			}
+			if len(d.calls) != 0 {
+				t.Fatalf("applied %q after a failed verification", d.calls)
+			}
+
+			statuses, err := changed.Status(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got Status
+			for _, s := range statuses {
+				if s.Version == tt.status.Version {
+					got = s
+				}
+			}
+			got.AppliedAt = time.Time{}
+			if got != tt.status {
+				t.Fatalf("Status() = %+v, want %+v", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestMigrator(t, testMigrations())
+	if err := m.Goto(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range statuses {
+		if statuses[i].Applied && statuses[i].AppliedAt.IsZero() {
+			t.Fatalf("status %d has no AppliedAt", statuses[i].Version)
+		}
+		statuses[i].AppliedAt = time.Time{}
+	}
+	want := []Status{
+		{Version: 1, Name: "create_users", Applied: true},
+		{Version: 2, Name: "add_email", Applied: true},
+		{Version: 3, Name: "add_index"},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("Status() = %+v, want %+v", statuses, want)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	up := &Migration{UpSQL: "CREATE TABLE users"}
+	both := &Migration{UpSQL: "CREATE TABLE users", DownSQL: "DROP TABLE users"}
+
+	sum := sha256.Sum256([]byte(up.UpSQL))
+	legacy := hex.EncodeToString(sum[:])
+	if up.Checksum() != legacy {
+		t.Fatalf("Checksum() without down = %s, want the up-only checksum %s", up.Checksum(), legacy)
+	}
+	if both.Checksum() == legacy {
+		t.Fatal("Checksum() does not cover the down script")
+	}
+	if (&Migration{UpFunc: func(context.Context) error { return nil }}).Checksum() != "" {
+		t.Fatal("Checksum() of a Go migration is not empty")
+	}
+
+	// 旧版本记录的 up 校验和仍然被接受
+	if !both.matches(legacy) || !both.matches(both.Checksum()) {
+		t.Fatal("matches() rejected a valid checksum")
+	}
+	if up.matches(both.Checksum()) || both.matches("") {
+		t.Fatal("matches() accepted a wrong checksum")
+	}
+}
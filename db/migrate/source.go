@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	fileName    = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_\-]+)\.(up|down)\.sql$`)
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+)
+
+// Load 从 fsys 的 dir 目录读取 SQL 迁移, 不符合命名规则的文件被忽略.
+// 磁盘目录使用 Load(os.DirFS(dir), "."), 嵌入文件使用 Load(embedFS, "migrations")
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", e.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", e.Name(), err)
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mg
+		} else if mg.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, mg.Name, match[2])
+		}
+		if match[3] == "up" {
+			mg.UpSQL = string(content)
+		} else {
+			mg.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.UpSQL == "" {
+			return nil, fmt.Errorf("migration %s has no up file", mg)
+		}
+		migrations = append(migrations, mg)
+	}
+	return migrations, nil
+}
+
+// Create 在 dir 中创建一对只含注释的 up/down 文件, 版本号为当前 UTC 时间 (yyyymmddhhmmss).
+// 返回创建的文件路径
+func Create(dir, name string) ([]string, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	var files []string
+	for _, d := range []Direction{Up, Down} {
+		file := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, d))
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return files, fmt.Errorf("failed to create migration file: %w", err)
+		}
+		_, err = fmt.Fprintf(f, "-- %s %s\n", name, d)
+		f.Close()
+		if err != nil {
+			return files, fmt.Errorf("failed to write migration file: %w", err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
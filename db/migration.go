@@ -0,0 +1,383 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/Dankko0w0/gospike/db/migrate"
+	"github.com/Dankko0w0/gospike/db/sqlbuilder"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migratable 由支持 schema 迁移的驱动实现
+type Migratable interface {
+	// MigrationDriver 返回把迁移记录保存在 table 中的 migrate.Driver, table 为空时使用 migrate.DefaultTable
+	MigrationDriver(table string) migrate.Driver
+}
+
+func migrationTable(table string) string {
+	if table == "" {
+		return migrate.DefaultTable
+	}
+	return table
+}
+
+// migrationLockKey 根据记录表名生成锁标识, 使用不同记录表的迁移互不阻塞
+func migrationLockKey(table string) string {
+	return "gospike_migrate:" + table
+}
+
+// PostgreSQL
+
+// MigrationDriver 返回 PostgreSQL 迁移驱动, 使用会话级 advisory lock 保证只有一个实例执行迁移
+func (p *PostgreSQL) MigrationDriver(table string) migrate.Driver {
+	return &pgMigrationDriver{p: p, table: migrationTable(table)}
+}
+
+type pgMigrationDriver struct {
+	p     *PostgreSQL
+	table string
+	// conn 持有 advisory lock 的连接, 锁属于会话, 必须在同一连接上释放
+	conn *pgxpool.Conn
+	// release 在 Unlock 时释放连接池, 持有锁期间重连不会关闭它
+	release func()
+}
+
+func (d *pgMigrationDriver) lockID() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(migrationLockKey(d.table)))
+	return int64(h.Sum64())
+}
+
+func (d *pgMigrationDriver) Lock(ctx context.Context) error {
+	pool, release, err := d.p.pool.acquire()
+	if err != nil {
+		return err
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		release()
+		return err
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", d.lockID()); err != nil {
+		conn.Release()
+		release()
+		return err
+	}
+	d.conn, d.release = conn, release
+	return nil
+}
+
+func (d *pgMigrationDriver) Unlock(ctx context.Context) error {
+	if d.conn == nil {
+		return nil
+	}
+	defer func() {
+		d.conn.Release()
+		d.release()
+		d.conn, d.release = nil, nil
+	}()
+	_, err := d.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", d.lockID())
+	return err
+}
+
+func (d *pgMigrationDriver) Applied(ctx context.Context) ([]migrate.Record, error) {
+	table, err := sqlbuilder.QuoteIdent(sqlbuilder.Postgres, d.table)
+	if err != nil {
+		return nil, err
+	}
+	db, release, err := d.p.querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(ctx, "CREATE TABLE IF NOT EXISTS "+table+` (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL
+)`)
+	release()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration table: %w", err)
+	}
+
+	var records []migrate.Record
+	if err := d.p.List(ctx, d.table, &sqlbuilder.Query{Order: []sqlbuilder.Order{{Column: "version"}}}, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (d *pgMigrationDriver) Apply(ctx context.Context, m *migrate.Migration, dir migrate.Direction) error {
+	return d.p.WithTx(ctx, func(ctx context.Context) error {
+		db, release, err := d.p.querier(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		script := m.UpSQL
+		if dir == migrate.Down {
+			script = m.DownSQL
+		}
+		// 无参数的 Exec 使用简单协议, 可以执行多条语句
+		if _, err := db.Exec(ctx, script); err != nil {
+			return err
+		}
+		query, args, err := migrationRecordSQL(sqlbuilder.Postgres, d.table, m, dir)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(ctx, query, args...)
+		return err
+	}, WithMaxRetries(0))
+}
+
+// SQL Server
+
+// MigrationDriver 返回 SQL Server 迁移驱动, 使用 sp_getapplock 保证只有一个实例执行迁移.
+// 迁移脚本可以用单独成行的 GO 分隔批处理
+func (s *SQLServer) MigrationDriver(table string) migrate.Driver {
+	return &sqlServerMigrationDriver{s: s, table: migrationTable(table)}
+}
+
+type sqlServerMigrationDriver struct {
+	s     *SQLServer
+	table string
+	// conn 持有会话级应用锁的连接
+	conn *sql.Conn
+	// release 在 Unlock 时释放连接池, 持有锁期间重连不会关闭它
+	release func()
+}
+
+func (d *sqlServerMigrationDriver) Lock(ctx context.Context) error {
+	db, release, err := d.s.db.acquire()
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		release()
+		return err
+	}
+
+	var result int
+	err = conn.QueryRowContext(ctx, `DECLARE @result INT;
+EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = -1;
+SELECT @result`, migrationLockKey(d.table)).Scan(&result)
+	if err == nil && result < 0 {
+		err = fmt.Errorf("sp_getapplock returned %d", result)
+	}
+	if err != nil {
+		conn.Close()
+		release()
+		return err
+	}
+	d.conn, d.release = conn, release
+	return nil
+}
+
+func (d *sqlServerMigrationDriver) Unlock(ctx context.Context) error {
+	if d.conn == nil {
+		return nil
+	}
+	defer func() {
+		d.conn.Close()
+		d.release()
+		d.conn, d.release = nil, nil
+	}()
+	_, err := d.conn.ExecContext(ctx, "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'", migrationLockKey(d.table))
+	return err
+}
+
+func (d *sqlServerMigrationDriver) Applied(ctx context.Context) ([]migrate.Record, error) {
+	table, err := sqlbuilder.QuoteIdent(sqlbuilder.SQLServer, d.table)
+	if err != nil {
+		return nil, err
+	}
+	db, release, err := d.s.querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	_, err = db.ExecContext(ctx, "IF OBJECT_ID(@p1, 'U') IS NULL CREATE TABLE "+table+` (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name NVARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	applied_at DATETIMEOFFSET NOT NULL
+)`, d.table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration table: %w", err)
+	}
+
+	query, args, err := sqlbuilder.Select(sqlbuilder.SQLServer, d.table).OrderBy("version").Build()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []migrate.Record
+	if err := scanRows(rows, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (d *sqlServerMigrationDriver) Apply(ctx context.Context, m *migrate.Migration, dir migrate.Direction) error {
+	return d.s.WithTx(ctx, func(ctx context.Context) error {
+		db, release, err := d.s.querier(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		script := m.UpSQL
+		if dir == migrate.Down {
+			script = m.DownSQL
+		}
+		for _, batch := range migrate.SplitStatements(script) {
+			if _, err := db.ExecContext(ctx, batch); err != nil {
+				return err
+			}
+		}
+		query, args, err := migrationRecordSQL(sqlbuilder.SQLServer, d.table, m, dir)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, query, args...)
+		return err
+	}, WithMaxRetries(0))
+}
+
+// migrationRecordSQL 生成写入或删除迁移记录的语句
+func migrationRecordSQL(d sqlbuilder.Dialect, table string, m *migrate.Migration, dir migrate.Direction) (string, []interface{}, error) {
+	if dir == migrate.Down {
+		return sqlbuilder.Delete(d, table, sqlbuilder.Eq("version", m.Version))
+	}
+	return sqlbuilder.Insert(d, table,
+		[]string{"version", "name", "checksum", "applied_at"},
+		[]interface{}{m.Version, m.Name, m.Checksum(), time.Now().UTC()})
+}
+
+// MongoDB
+
+// MigrationDriver 返回 MongoDB 迁移驱动, 只支持 UpFunc/DownFunc 形式的 Go 函数迁移,
+// 如创建索引和集合. 迁移不在事务中执行, 失败时需要函数自身保证可重入.
+// 锁是 <table>_lock 集合中的一个文档, 进程崩溃后需要手动删除
+func (m *MongoDB) MigrationDriver(table string) migrate.Driver {
+	return &mongoMigrationDriver{m: m, table: migrationTable(table)}
+}
+
+type mongoMigrationDriver struct {
+	m     *MongoDB
+	table string
+}
+
+// mongoMigrationRecord 是迁移记录在 MongoDB 中的结构, 版本号作为 _id
+type mongoMigrationRecord struct {
+	Version   int64     `bson:"_id"`
+	Name      string    `bson:"name"`
+	Checksum  string    `bson:"checksum"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+const mongoLockPollInterval = time.Second
+
+// collection 在当前客户端的数据库上调用 fn
+func (d *mongoMigrationDriver) collection(ctx context.Context, name string, fn func(coll *mongo.Collection) error) error {
+	db, release, err := d.m.database(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(db.Collection(name))
+}
+
+func (d *mongoMigrationDriver) Lock(ctx context.Context) error {
+	lock := bson.M{"_id": migrationLockKey(d.table)}
+	for {
+		err := d.collection(ctx, d.table+"_lock", func(coll *mongo.Collection) error {
+			lock["locked_at"] = time.Now().UTC()
+			_, err := coll.InsertOne(ctx, lock)
+			return err
+		})
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(mongoLockPollInterval):
+		}
+	}
+}
+
+func (d *mongoMigrationDriver) Unlock(ctx context.Context) error {
+	return d.collection(ctx, d.table+"_lock", func(coll *mongo.Collection) error {
+		_, err := coll.DeleteOne(ctx, bson.M{"_id": migrationLockKey(d.table)})
+		return err
+	})
+}
+
+func (d *mongoMigrationDriver) Applied(ctx context.Context) ([]migrate.Record, error) {
+	var docs []mongoMigrationRecord
+	err := d.collection(ctx, d.table, func(coll *mongo.Collection) error {
+		cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &docs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]migrate.Record, len(docs))
+	for i, doc := range docs {
+		records[i] = migrate.Record(doc)
+	}
+	return records, nil
+}
+
+func (d *mongoMigrationDriver) Apply(ctx context.Context, m *migrate.Migration, dir migrate.Direction) error {
+	fn := m.UpFunc
+	if dir == migrate.Down {
+		fn = m.DownFunc
+	}
+	if fn == nil {
+		return errors.New("MongoDB only supports Go function migrations")
+	}
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	return d.collection(ctx, d.table, func(coll *mongo.Collection) error {
+		if dir == migrate.Down {
+			_, err := coll.DeleteOne(ctx, bson.M{"_id": m.Version})
+			return err
+		}
+		_, err := coll.InsertOne(ctx, mongoMigrationRecord{
+			Version:   m.Version,
+			Name:      m.Name,
+			Checksum:  m.Checksum(),
+			AppliedAt: time.Now().UTC(),
+		})
+		return err
+	})
+}
@@ -2,118 +2,363 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
+	"net/url"
+	"strings"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 type MongoDB struct {
-	config     *Config
-	client     *mongo.Client
-	db         *mongo.Database
-	connected  bool
-	maxRetries int
+	config *Config
+	db     clientHolder[*mongo.Database]
+	retrier
 }
 
 func NewMongoDB(config *Config) *MongoDB {
 	return &MongoDB{
-		config:     config,
-		maxRetries: 3,
+		config:  config,
+		retrier: newRetrier(mongoConnRetryable),
 	}
 }
 
 func (m *MongoDB) Connect(ctx context.Context) error {
-	uri := fmt.Sprintf("mongodb://%s:%s@%s:%d",
-		m.config.Username,
-		m.config.Password,
-		m.config.Host,
-		m.config.Port,
-	)
-
-	clientOptions := options.Client().ApplyURI(uri)
+	opts, err := m.config.ParseOptions()
+	if err != nil {
+		return err
+	}
+	clientOptions := options.Client().ApplyURI(m.connString())
+	if err := applyMongoOptions(clientOptions, opts, m.config.Host); err != nil {
+		return err
+	}
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		return fmt.Errorf("failed to connect to MongoDB: %v", err)
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
 
 	err = client.Ping(ctx, readpref.Primary())
 	if err != nil {
-		return fmt.Errorf("failed to ping MongoDB: %v", err)
+		client.Disconnect(ctx)
+		return fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
-	m.client = client
-	m.db = client.Database(m.config.Database)
-	m.connected = true
+	// 已连接时替换旧客户端, 旧客户端在进行中的调用结束后关闭
+	m.db.replace(ctx, "mongodb", client.Database(m.databaseName()), func(ctx context.Context) error {
+		if err := client.Disconnect(ctx); err != nil {
+			return fmt.Errorf("failed to disconnect from MongoDB: %v", err)
+		}
+		return nil
+	})
 	return nil
 }
 
-func (m *MongoDB) Disconnect(ctx context.Context) error {
-	if m.client != nil {
-		err := m.client.Disconnect(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to disconnect from MongoDB: %v", err)
-		}
-		m.connected = false
+// connString 返回 DSN, 未配置时根据各字段构造 URI, 以 Database 作为认证数据库
+func (m *MongoDB) connString() string {
+	if m.config.DSN != "" {
+		return m.config.DSN
 	}
-	return nil
+	u := m.config.buildURL("mongodb")
+	u.Path = "/"
+	if m.config.Database != "" && u.User != nil {
+		u.RawQuery = url.Values{"authSource": {m.config.Database}}.Encode()
+	}
+	return u.String()
+}
+
+// databaseName 返回 Database, 未配置时使用 DSN 路径中的数据库名
+func (m *MongoDB) databaseName() string {
+	if m.config.Database != "" || m.config.DSN == "" {
+		return m.config.Database
+	}
+	if u, err := url.Parse(m.config.DSN); err == nil {
+		return strings.TrimPrefix(u.Path, "/")
+	}
+	return ""
+}
+
+// Disconnect 等待进行中的调用结束后断开客户端, 可以重复调用
+func (m *MongoDB) Disconnect(ctx context.Context) error {
+	return m.db.disconnect(ctx)
 }
 
 func (m *MongoDB) Ping(ctx context.Context) error {
-	if m.client == nil {
-		return fmt.Errorf("database not connected")
+	db, release, err := m.db.acquire()
+	if err != nil {
+		return err
 	}
-	return m.client.Ping(ctx, readpref.Primary())
+	defer release()
+	return db.Client().Ping(ctx, readpref.Primary())
 }
 
 func (m *MongoDB) IsConnected() bool {
-	return m.connected
+	return m.db.connected()
 }
 
+// Reconnect 按 RetryPolicy 建立新客户端并替换旧客户端, 见 SetRetryPolicy
 func (m *MongoDB) Reconnect(ctx context.Context) error {
-	m.Disconnect(ctx)
+	return reconnect(ctx, "mongodb", m.RetryPolicy(), m.Connect)
+}
 
-	for i := 0; i < m.maxRetries; i++ {
-		err := m.Connect(ctx)
-		if err == nil {
-			return nil
-		}
-		time.Sleep(time.Second * time.Duration(i+1))
-	}
-	return fmt.Errorf("failed to reconnect after %d attempts", m.maxRetries)
+// KeyName 返回实体主键的 bson 字段名, 见 KeyNamer
+func (m *MongoDB) KeyName(e *Entity) string {
+	return e.DocumentKey
 }
 
 // CRUD operations
+// filter 可以是 BSON 文档或 q.Query, 为 nil 时匹配所有文档. Update 的 update 不是 $set 等操作符文档时按 $set 处理.
+// Update 和 Delete 与 SQL 驱动一致, 影响所有匹配的文档; filter 为空时返回 ErrNoCondition, 影响所有文档需要传入 q.All()
 func (m *MongoDB) Create(ctx context.Context, collection string, data interface{}) error {
-	coll := m.db.Collection(collection)
-	_, err := coll.InsertOne(ctx, data)
+	db, release, err := m.database(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = db.Collection(collection).InsertOne(ctx, data)
 	return err
 }
 
 func (m *MongoDB) Read(ctx context.Context, collection string, filter interface{}, result interface{}) error {
-	coll := m.db.Collection(collection)
-	return coll.FindOne(ctx, filter).Decode(result)
+	mq, err := mongoFind(filter)
+	if err != nil {
+		return err
+	}
+	db, release, err := m.database(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	coll := db.Collection(collection)
+	err = coll.FindOne(ctx, mq.filter, mq.findOneOptions()).Decode(result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &NotFoundError{Collection: collection}
+	}
+	return err
 }
 
 func (m *MongoDB) Update(ctx context.Context, collection string, filter interface{}, update interface{}) error {
-	coll := m.db.Collection(collection)
-	_, err := coll.UpdateOne(ctx, filter, update)
+	where, err := mongoMutationFilter(collection, filter)
+	if err != nil {
+		return err
+	}
+	db, release, err := m.database(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	coll := db.Collection(collection)
+	_, err = coll.UpdateMany(ctx, where, mongoUpdate(update))
 	return err
 }
 
 func (m *MongoDB) Delete(ctx context.Context, collection string, filter interface{}) error {
-	coll := m.db.Collection(collection)
-	_, err := coll.DeleteOne(ctx, filter)
+	where, err := mongoMutationFilter(collection, filter)
+	if err != nil {
+		return err
+	}
+	db, release, err := m.database(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	coll := db.Collection(collection)
+	_, err = coll.DeleteMany(ctx, where)
 	return err
 }
 
 func (m *MongoDB) List(ctx context.Context, collection string, filter interface{}, results interface{}) error {
-	coll := m.db.Collection(collection)
-	cursor, err := coll.Find(ctx, filter)
+	mq, err := mongoFind(filter)
+	if err != nil {
+		return err
+	}
+	db, release, err := m.database(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	coll := db.Collection(collection)
+	cursor, err := coll.Find(ctx, mq.filter, mq.findOptions())
 	if err != nil {
 		return err
 	}
 	return cursor.All(ctx, results)
 }
+
+// Count 返回满足 filter 的文档数, 见 Counter
+func (m *MongoDB) Count(ctx context.Context, collection string, filter interface{}) (int64, error) {
+	mq, err := mongoFind(filter)
+	if err != nil {
+		return 0, err
+	}
+	db, release, err := m.database(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	return db.Collection(collection).CountDocuments(ctx, mq.filter)
+}
+
+// ListAfter 按 key 做键集分页, 见 KeysetLister
+func (m *MongoDB) ListAfter(ctx context.Context, collection string, filter interface{}, key string, after interface{}, limit int, results interface{}) error {
+	mq, err := mongoFind(filter)
+	if err != nil {
+		return err
+	}
+	query := mq.filter
+	if after != nil {
+		query = bson.M{"$and": bson.A{query, bson.M{key: bson.M{"$gt": after}}}}
+	}
+	mq.sort = bson.D{{Key: key, Value: 1}}
+	mq.limit = int64(limit)
+	mq.skip = 0
+	opts := mq.findOptions()
+
+	db, release, err := m.database(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	cursor, err := db.Collection(collection).Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+// WithTx 在会话事务中执行 fn, 见 Transactor. 需要副本集或分片集群.
+// MongoDB 不支持保存点, 嵌套调用直接加入外层事务, 内层错误会导致整个事务回滚.
+// 带 TransientTransactionError 标签的错误重试整个事务, UnknownTransactionCommitResult 只重试提交
+func (m *MongoDB) WithTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if txFromContext(ctx, m) != nil {
+		return fn(ctx)
+	}
+	o := newTxOptions(opts)
+
+	txnOpts := options.Transaction().SetWriteConcern(writeconcern.Majority())
+	switch o.Isolation {
+	case LevelSnapshot, LevelSerializable:
+		txnOpts.SetReadConcern(readconcern.Snapshot())
+	case LevelDefault:
+	default:
+		txnOpts.SetReadConcern(readconcern.Majority())
+	}
+
+	// 事务期间持有客户端, 重连不会关闭它
+	db, release, err := m.db.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	return retryTx(ctx, "mongodb", o.MaxRetries, mongoHasLabel("TransientTransactionError"), func() error {
+		return mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+			if err := session.StartTransaction(txnOpts); err != nil {
+				return err
+			}
+			txCtx := contextWithTx(sc, m, session)
+			return runTx(txCtx, fn,
+				func() error {
+					return retryTx(ctx, "mongodb", o.MaxRetries, mongoHasLabel("UnknownTransactionCommitResult"), func() error {
+						return session.CommitTransaction(sc)
+					})
+				},
+				func() error { return session.AbortTransaction(sc) })
+		})
+	})
+}
+
+// database 返回 ctx 中事务所属客户端的数据库, 没有事务时返回当前客户端的数据库. 使用结束后调用 release
+func (m *MongoDB) database(ctx context.Context) (db *mongo.Database, release func(), err error) {
+	if session, ok := txFromContext(ctx, m).(mongo.Session); ok {
+		return session.Client().Database(m.databaseName()), func() {}, nil
+	}
+	return m.db.acquire()
+}
+
+// mongoHasLabel 返回判断错误是否带有指定标签的函数
+func mongoHasLabel(label string) func(error) bool {
+	return func(err error) bool {
+		var labeled mongo.LabeledError
+		return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+	}
+}
+
+// mongoFilter 将 nil filter 转为匹配所有文档的空文档
+func mongoFilter(filter interface{}) interface{} {
+	if filter == nil {
+		return bson.M{}
+	}
+	return filter
+}
+
+// mongoUpdate 将不以操作符开头的文档包装为 $set, 无法编码为文档的值 (如聚合管道) 原样返回
+func mongoUpdate(update interface{}) interface{} {
+	raw, err := bson.Marshal(update)
+	if err != nil {
+		return update
+	}
+	elem, err := bson.Raw(raw).IndexErr(0)
+	if err != nil || strings.HasPrefix(elem.Key(), "$") {
+		return update
+	}
+	return bson.M{"$set": update}
+}
+
+// applyMongoOptions 将连接池, 超时, TLS 和应用名称选项应用到客户端配置.
+// ReadTimeout 和 WriteTimeout 取较大值作为 socket 超时
+func applyMongoOptions(clientOptions *options.ClientOptions, opts *Options, host string) error {
+	if opts.MaxConns > 0 {
+		clientOptions.SetMaxPoolSize(uint64(opts.MaxConns))
+	}
+	if opts.MinConns > 0 {
+		clientOptions.SetMinPoolSize(uint64(opts.MinConns))
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		clientOptions.SetMaxConnIdleTime(opts.ConnMaxIdleTime)
+	}
+	if opts.ConnectTimeout > 0 {
+		clientOptions.SetConnectTimeout(opts.ConnectTimeout)
+	}
+	if timeout := max(opts.ReadTimeout, opts.WriteTimeout); timeout > 0 {
+		clientOptions.SetSocketTimeout(timeout)
+	}
+	if opts.AppName != "" {
+		clientOptions.SetAppName(opts.AppName)
+	}
+
+	tlsConfig, err := opts.tlsConfig(host)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+	return nil
+}
+
+// mongoConnRetryable 判断连接错误是否可以重试, 认证失败不重试
+func mongoConnRetryable(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code != 18 && cmdErr.Code != 13
+	}
+	return !strings.Contains(err.Error(), "auth error")
+}
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Dankko0w0/gospike/db/q"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoQuery 是翻译后的 MongoDB 查询
+type mongoQuery struct {
+	filter     interface{}
+	sort       bson.D
+	projection bson.D
+	limit      int64
+	skip       int64
+}
+
+// mongoFind 将 filter 转换为 MongoDB 查询. q.Query 被翻译为 BSON, 其他类型原样作为 filter
+func mongoFind(filter interface{}) (*mongoQuery, error) {
+	var query q.Query
+	switch f := filter.(type) {
+	case q.Query:
+		query = f
+	case *q.Query:
+		if f != nil {
+			query = *f
+		}
+	default:
+		return &mongoQuery{filter: mongoFilter(filter)}, nil
+	}
+
+	doc, err := bsonExpr(query.Filter())
+	if err != nil {
+		return nil, err
+	}
+	mq := &mongoQuery{
+		filter: doc,
+		limit:  int64(query.LimitValue()),
+		skip:   int64(query.OffsetValue()),
+	}
+	for _, s := range query.SortFields() {
+		dir := 1
+		if s.Desc {
+			dir = -1
+		}
+		mq.sort = append(mq.sort, bson.E{Key: s.Field, Value: dir})
+	}
+	for _, f := range query.Fields() {
+		mq.projection = append(mq.projection, bson.E{Key: f, Value: 1})
+	}
+	return mq, nil
+}
+
+func (mq *mongoQuery) findOptions() *options.FindOptions {
+	opts := options.Find()
+	if mq.sort != nil {
+		opts.SetSort(mq.sort)
+	}
+	if mq.projection != nil {
+		opts.SetProjection(mq.projection)
+	}
+	if mq.limit > 0 {
+		opts.SetLimit(mq.limit)
+	}
+	if mq.skip > 0 {
+		opts.SetSkip(mq.skip)
+	}
+	return opts
+}
+
+func (mq *mongoQuery) findOneOptions() *options.FindOneOptions {
+	opts := options.FindOne()
+	if mq.sort != nil {
+		opts.SetSort(mq.sort)
+	}
+	if mq.projection != nil {
+		opts.SetProjection(mq.projection)
+	}
+	if mq.skip > 0 {
+		opts.SetSkip(mq.skip)
+	}
+	return opts
+}
+
+// bsonExpr 将 q 表达式翻译为 BSON 过滤文档
+func bsonExpr(e q.Expr) (bson.D, error) {
+	switch e := e.(type) {
+	case nil:
+		return bson.D{}, nil
+	case q.Cond:
+		value, err := bsonCondValue(e)
+		if err != nil {
+			return nil, err
+		}
+		return bson.D{{Key: e.Field, Value: value}}, nil
+	case q.And:
+		if len(e) == 1 {
+			return bsonExpr(e[0])
+		}
+		return bsonGroup("$and", e)
+	case q.Or:
+		if len(e) == 1 {
+			return bsonExpr(e[0])
+		}
+		return bsonGroup("$or", e)
+	case q.MatchAll:
+		return bson.D{}, nil
+	case q.Not:
+		if e.Expr == nil {
+			return nil, fmt.Errorf("not expression without an inner expression")
+		}
+		return bsonGroup("$nor", []q.Expr{e.Expr})
+	}
+	return nil, fmt.Errorf("unsupported expression %T", e)
+}
+
+// matchNothing 不匹配任何文档, 用于空的 $or (MongoDB 不接受空数组)
+var matchNothing = bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: bson.A{}}}}}
+
+func bsonGroup(op string, exprs []q.Expr) (bson.D, error) {
+	if len(exprs) == 0 {
+		if op == "$or" {
+			return matchNothing, nil
+		}
+		return bson.D{}, nil
+	}
+	docs := make(bson.A, len(exprs))
+	for i, e := range exprs {
+		doc, err := bsonExpr(e)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+	}
+	return bson.D{{Key: op, Value: docs}}, nil
+}
+
+func bsonCondValue(c q.Cond) (interface{}, error) {
+	switch c.Op {
+	case q.OpEq:
+		return c.Value, nil
+	case q.OpNe:
+		// 与 SQL 的 <> 一致, 不匹配 null 和字段不存在的文档
+		return bson.D{{Key: "$nin", Value: bson.A{c.Value, nil}}}, nil
+	case q.OpGt:
+		return bson.D{{Key: "$gt", Value: c.Value}}, nil
+	case q.OpGte:
+		return bson.D{{Key: "$gte", Value: c.Value}}, nil
+	case q.OpLt:
+		return bson.D{{Key: "$lt", Value: c.Value}}, nil
+	case q.OpLte:
+		return bson.D{{Key: "$lte", Value: c.Value}}, nil
+	case q.OpIn:
+		return bson.D{{Key: "$in", Value: c.Value}}, nil
+	case q.OpNotIn:
+		values, ok := c.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return bson.D{{Key: "$nin", Value: c.Value}}, nil
+		}
+		// 与 SQL 的 NOT IN 一致, 不匹配 null 和字段不存在的文档; 空集合与 SQL 相同, 匹配所有文档
+		return bson.D{{Key: "$nin", Value: append(append(bson.A{}, values...), nil)}}, nil
+	case q.OpLike:
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("like pattern for %s must be a string, got %T", c.Field, c.Value)
+		}
+		return bson.D{{Key: "$regex", Value: likeToRegex(pattern)}}, nil
+	case q.OpIsNull:
+		return nil, nil
+	case q.OpNotNull:
+		return bson.D{{Key: "$ne", Value: nil}}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %q", c.Op)
+}
+
+// mongoMutationFilter 返回 Update 和 Delete 的过滤文档.
+// 与 SQL 驱动一致, 过滤文档为空时返回 ErrNoCondition, 除非 filter 含有 q.MatchAll (q.All())
+func mongoMutationFilter(collection string, filter interface{}) (interface{}, error) {
+	mq, err := mongoFind(filter)
+	if err != nil {
+		return nil, err
+	}
+	var e q.Expr
+	switch f := filter.(type) {
+	case q.Query:
+		e = f.Filter()
+	case *q.Query:
+		if f != nil {
+			e = f.Filter()
+		}
+	}
+	if isEmptyDocument(mq.filter) && !hasMatchAll(e) {
+		return nil, fmt.Errorf("%s: %w", collection, ErrNoCondition)
+	}
+	return mq.filter, nil
+}
+
+// isEmptyDocument 判断 filter 是否编码为空文档, 无法编码时交给驱动报错
+func isEmptyDocument(filter interface{}) bool {
+	raw, err := bson.Marshal(filter)
+	if err != nil {
+		return false
+	}
+	elems, err := bson.Raw(raw).Elements()
+	return err == nil && len(elems) == 0
+}
+
+func hasMatchAll(e q.Expr) bool {
+	switch e := e.(type) {
+	case q.MatchAll:
+		return true
+	case q.And:
+		for _, sub := range e {
+			if hasMatchAll(sub) {
+				return true
+			}
+		}
+	case q.Or:
+		for _, sub := range e {
+			if hasMatchAll(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// likeToRegex 将 SQL LIKE 模式转换为锚定的正则表达式
+func likeToRegex(pattern string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteByte('.')
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteByte('$')
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/db/q"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBSONExprTranslation(t *testing.T) {
+	tests := []struct {
+		name  string
+		query q.Query
+		want  bson.D
+	}{
+		{"empty or matches nothing", q.Where(q.Or{}), matchNothing},
+		{"any without alternatives", q.Any(), matchNothing},
+		{"ne excludes null", q.Ne("status", "x"), bson.D{{Key: "status", Value: bson.D{{Key: "$nin", Value: bson.A{"x", nil}}}}}},
+		{"not in excludes null", q.NotIn("id", 1, 2), bson.D{{Key: "id", Value: bson.D{{Key: "$nin", Value: bson.A{1, 2, nil}}}}}},
+		{"all", q.All(), bson.D{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bsonExpr(tt.query.Filter())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBSONExprRejectsNotWithoutExpr(t *testing.T) {
+	if _, err := bsonExpr(q.Not{}); err == nil {
+		t.Fatal("expected an error for q.Not without an inner expression")
+	}
+}
+
+func TestMongoMutationsRejectEmptyFilters(t *testing.T) {
+	ctx := context.Background()
+	m := NewMongoDB(&Config{})
+	for _, filter := range []interface{}{nil, q.New(), bson.M{}, bson.D{}, q.Where(q.And{})} {
+		if err := m.Delete(ctx, "users", filter); !errors.Is(err, ErrNoCondition) {
+			t.Errorf("Delete(%#v): got %v, want ErrNoCondition", filter, err)
+		}
+		if err := m.Update(ctx, "users", filter, bson.M{"name": "x"}); !errors.Is(err, ErrNoCondition) {
+			t.Errorf("Update(%#v): got %v, want ErrNoCondition", filter, err)
+		}
+	}
+	for _, filter := range []interface{}{q.All(), q.Eq("id", 1), bson.M{"id": 1}} {
+		if err := m.Delete(ctx, "users", filter); !errors.Is(err, ErrNotConnected) {
+			t.Errorf("Delete(%#v): got %v, want ErrNotConnected", filter, err)
+		}
+	}
+}
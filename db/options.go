@@ -0,0 +1,168 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Options 是从 Config.Options 解析出的驱动选项, 键名不区分大小写, 时长使用 "5s" 这样的字符串.
+// 驱动不支持的选项被忽略, 零值表示使用驱动默认值
+type Options struct {
+	// 连接池
+	MaxConns        int           `mapstructure:"maxConns"`
+	MinConns        int           `mapstructure:"minConns"`
+	MaxIdleConns    int           `mapstructure:"maxIdleConns"` // SQL Server
+	ConnMaxIdleTime time.Duration `mapstructure:"connMaxIdleTime"`
+	ConnMaxLifetime time.Duration `mapstructure:"connMaxLifetime"`
+
+	// 超时
+	ConnectTimeout time.Duration `mapstructure:"connectTimeout"`
+	ReadTimeout    time.Duration `mapstructure:"readTimeout"`  // MongoDB, Redis
+	WriteTimeout   time.Duration `mapstructure:"writeTimeout"` // MongoDB, Redis
+
+	// SSLMode 取值 disable, allow, prefer, require, verify-ca, verify-full.
+	// PostgreSQL 原样使用, 其他驱动在 require 及以上时启用 TLS.
+	// 与 libpq 一致, require 配置了 TLS.CAFile 时按 verify-ca 校验证书链
+	SSLMode string     `mapstructure:"sslmode"`
+	TLS     TLSOptions `mapstructure:"tls"`
+
+	// DB 是 Redis 数据库编号
+	DB int `mapstructure:"db"`
+	// AppName 是报告给服务器的应用名称
+	AppName string `mapstructure:"appName"`
+}
+
+// TLSOptions TLS 配置, 设置任一文件时自动启用
+type TLSOptions struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	CAFile             string `mapstructure:"caFile"`
+	CertFile           string `mapstructure:"certFile"`
+	KeyFile            string `mapstructure:"keyFile"`
+	ServerName         string `mapstructure:"serverName"`
+	InsecureSkipVerify bool   `mapstructure:"insecureSkipVerify"`
+}
+
+// ErrInvalidOptions 表示 Config.Options 无法解析或校验失败, Reconnect 不会重试此类错误
+var ErrInvalidOptions = errors.New("invalid database options")
+
+var sslModes = map[string]bool{
+	"": true, "disable": true, "allow": true, "prefer": true,
+	"require": true, "verify-ca": true, "verify-full": true,
+}
+
+// ParseOptions 解析并校验 Config.Options, 未知的键返回错误
+func (c *Config) ParseOptions() (*Options, error) {
+	opts := &Options{}
+	if len(c.Options) == 0 {
+		return opts, nil
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		Result:           opts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(c.Options); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	if err := opts.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	return opts, nil
+}
+
+func (o *Options) validate() error {
+	switch {
+	case o.MaxConns < 0 || o.MinConns < 0 || o.MaxIdleConns < 0:
+		return fmt.Errorf("connection counts must not be negative")
+	case o.MaxConns > 0 && o.MinConns > o.MaxConns:
+		return fmt.Errorf("minConns %d exceeds maxConns %d", o.MinConns, o.MaxConns)
+	case o.MaxConns > 0 && o.MaxIdleConns > o.MaxConns:
+		return fmt.Errorf("maxIdleConns %d exceeds maxConns %d", o.MaxIdleConns, o.MaxConns)
+	case o.ConnMaxIdleTime < 0 || o.ConnMaxLifetime < 0 ||
+		o.ConnectTimeout < 0 || o.ReadTimeout < 0 || o.WriteTimeout < 0:
+		return fmt.Errorf("durations must not be negative")
+	case !sslModes[o.SSLMode]:
+		return fmt.Errorf("unknown sslmode %q", o.SSLMode)
+	case (o.TLS.CertFile == "") != (o.TLS.KeyFile == ""):
+		return fmt.Errorf("tls certFile and keyFile must be set together")
+	case o.TLS.InsecureSkipVerify && o.TLS.CAFile != "":
+		return fmt.Errorf("tls insecureSkipVerify would ignore caFile")
+	case o.DB < 0:
+		return fmt.Errorf("db index must not be negative")
+	}
+	return nil
+}
+
+// tlsEnabled 判断非 PostgreSQL 驱动是否需要 TLS
+func (o *Options) tlsEnabled() bool {
+	switch o.SSLMode {
+	case "require", "verify-ca", "verify-full":
+		return true
+	case "disable":
+		return false
+	}
+	return o.TLS.Enabled || o.TLS.CAFile != "" || o.TLS.CertFile != ""
+}
+
+// tlsConfig 根据选项构造 TLS 配置, 不需要 TLS 时返回 nil
+func (o *Options) tlsConfig(host string) (*tls.Config, error) {
+	if !o.tlsEnabled() {
+		return nil, nil
+	}
+
+	// require 没有 CA 时只加密不校验, 有 CA 时与 verify-ca 相同
+	verifyChain := o.SSLMode == "verify-ca" || (o.SSLMode == "require" && o.TLS.CAFile != "")
+	cfg := &tls.Config{
+		ServerName:         o.TLS.ServerName,
+		InsecureSkipVerify: o.TLS.InsecureSkipVerify || (o.SSLMode == "require" && o.TLS.CAFile == ""),
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+
+	if o.TLS.CAFile != "" {
+		pem, err := os.ReadFile(o.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls caFile: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.TLS.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if verifyChain && !cfg.InsecureSkipVerify {
+		// verify-ca 只校验证书链, 不校验主机名
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("server sent no certificate")
+			}
+			verifyOpts := x509.VerifyOptions{Roots: cfg.RootCAs, Intermediates: x509.NewCertPool()}
+			for _, cert := range cs.PeerCertificates[1:] {
+				verifyOpts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(verifyOpts)
+			return err
+		}
+	}
+	if o.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.TLS.CertFile, o.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
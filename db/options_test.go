@@ -0,0 +1,166 @@
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseOptions(t *testing.T) {
+	cfg := Config{Options: map[string]interface{}{
+		"MAXCONNS":        "10", // 键名不区分大小写, 字符串按弱类型转换
+		"minConns":        2,
+		"connMaxIdleTime": "90s",
+		"connectTimeout":  "1m30s",
+		"sslmode":         "verify-full",
+		"tls":             map[string]interface{}{"serverName": "db.internal"},
+	}}
+	opts, err := cfg.ParseOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.MaxConns != 10 || opts.MinConns != 2 {
+		t.Errorf("connection counts = %d/%d, want 10/2", opts.MaxConns, opts.MinConns)
+	}
+	if opts.ConnMaxIdleTime != 90*time.Second || opts.ConnectTimeout != 90*time.Second {
+		t.Errorf("durations = %v/%v, want 90s", opts.ConnMaxIdleTime, opts.ConnectTimeout)
+	}
+	if opts.SSLMode != "verify-full" || opts.TLS.ServerName != "db.internal" {
+		t.Errorf("tls options = %q/%q", opts.SSLMode, opts.TLS.ServerName)
+	}
+
+	empty, err := (&Config{}).ParseOptions()
+	if err != nil || *empty != (Options{}) {
+		t.Errorf("empty options = %+v, %v", empty, err)
+	}
+}
+
+func TestParseOptionsErrors(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unknown key":            {"maxConnections": 10},
+		"unknown tls key":        {"tls": map[string]interface{}{"ca": "x"}},
+		"bad duration":           {"connectTimeout": "five seconds"},
+		"negative count":         {"maxConns": -1},
+		"min exceeds max":        {"maxConns": 2, "minConns": 3},
+		"idle exceeds max":       {"maxConns": 2, "maxIdleConns": 3},
+		"negative duration":      {"readTimeout": "-1s"},
+		"unknown sslmode":        {"sslmode": "strict"},
+		"cert without key":       {"tls": map[string]interface{}{"certFile": "client.pem"}},
+		"insecure with ca":       {"tls": map[string]interface{}{"caFile": "ca.pem", "insecureSkipVerify": true}},
+		"negative redis db":      {"db": -1},
+		"wrong type for integer": {"maxConns": "many"},
+	}
+	for name, options := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := (&Config{Options: options}).ParseOptions()
+			if !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("got %v, want ErrInvalidOptions", err)
+			}
+		})
+	}
+}
+
+// writeCertPEM 把证书写入临时文件并返回路径
+func writeCertPEM(t *testing.T, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// otherCA 生成一个与测试服务器无关的自签名 CA
+func otherCA(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestTLSConfigSSLModes(t *testing.T) {
+	// httptest 的证书对 127.0.0.1 和 example.com 有效
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	serverCA := writeCertPEM(t, server.Certificate().Raw)
+	wrongCA := writeCertPEM(t, otherCA(t))
+
+	tests := []struct {
+		name       string
+		opts       Options
+		host       string
+		wantNil    bool
+		wantDialOK bool
+	}{
+		{"no tls", Options{}, "127.0.0.1", true, false},
+		{"disable", Options{SSLMode: "disable", TLS: TLSOptions{Enabled: true}}, "127.0.0.1", true, false},
+		{"prefer", Options{SSLMode: "prefer"}, "127.0.0.1", true, false},
+		{"enabled without mode", Options{TLS: TLSOptions{Enabled: true, CAFile: serverCA}}, "127.0.0.1", false, true},
+		{"require skips verification", Options{SSLMode: "require"}, "127.0.0.1", false, true},
+		{"require verifies against ca", Options{SSLMode: "require", TLS: TLSOptions{CAFile: serverCA}}, "wrong.host", false, true},
+		{"require rejects wrong ca", Options{SSLMode: "require", TLS: TLSOptions{CAFile: wrongCA}}, "127.0.0.1", false, false},
+		{"verify-ca ignores host name", Options{SSLMode: "verify-ca", TLS: TLSOptions{CAFile: serverCA}}, "wrong.host", false, true},
+		{"verify-ca rejects wrong ca", Options{SSLMode: "verify-ca", TLS: TLSOptions{CAFile: wrongCA}}, "127.0.0.1", false, false},
+		{"verify-full", Options{SSLMode: "verify-full", TLS: TLSOptions{CAFile: serverCA}}, "127.0.0.1", false, true},
+		{"verify-full checks host name", Options{SSLMode: "verify-full", TLS: TLSOptions{CAFile: serverCA}}, "wrong.host", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.opts.tlsConfig(tt.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (cfg == nil) != tt.wantNil {
+				t.Fatalf("tlsConfig() = %v, want nil: %v", cfg, tt.wantNil)
+			}
+			if cfg == nil {
+				return
+			}
+
+			conn, err := tls.Dial("tcp", server.Listener.Addr().String(), cfg)
+			if err == nil {
+				conn.Close()
+			}
+			if (err == nil) != tt.wantDialOK {
+				t.Errorf("handshake error = %v, want success: %v", err, tt.wantDialOK)
+			}
+		})
+	}
+}
+
+func TestSQLServerParamsRequireWithCA(t *testing.T) {
+	params, err := sqlServerParams(&Options{SSLMode: "require"})
+	if err != nil || params.Get("trustservercertificate") != "true" {
+		t.Errorf("require without ca: %v, %v", params, err)
+	}
+	params, err = sqlServerParams(&Options{SSLMode: "require", TLS: TLSOptions{CAFile: "ca.pem"}})
+	if err != nil || params.Has("trustservercertificate") || params.Get("certificate") != "ca.pem" {
+		t.Errorf("require with ca should verify the certificate: %v, %v", params, err)
+	}
+}
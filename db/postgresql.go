@@ -2,104 +2,376 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
+	"net/url"
+	"strings"
 
+	"github.com/Dankko0w0/gospike/db/retry"
+	"github.com/Dankko0w0/gospike/db/sqlbuilder"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type PostgreSQL struct {
-	config     *Config
-	pool       *pgxpool.Pool
-	connected  bool
-	maxRetries int
+	config *Config
+	pool   clientHolder[*pgxpool.Pool]
+	retrier
 }
 
 func NewPostgreSQL(config *Config) *PostgreSQL {
 	return &PostgreSQL{
-		config:     config,
-		maxRetries: 3,
+		config:  config,
+		retrier: newRetrier(pgConnRetryable),
 	}
 }
 
 func (p *PostgreSQL) Connect(ctx context.Context) error {
-	connStr := fmt.Sprintf("postgresql://%s:%s@%s:%d/%s",
-		p.config.Username,
-		p.config.Password,
-		p.config.Host,
-		p.config.Port,
-		p.config.Database,
-	)
+	opts, err := p.config.ParseOptions()
+	if err != nil {
+		return err
+	}
+	connStr, err := withParams(p.connString(), pgParams(opts))
+	if err != nil {
+		return err
+	}
 
 	poolConfig, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
-		return fmt.Errorf("unable to parse config: %v", err)
+		return retry.Permanent(fmt.Errorf("unable to parse config: %w", err))
 	}
+	applyPgOptions(poolConfig, opts)
 
 	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
 	if err != nil {
-		return fmt.Errorf("unable to connect to database: %v", err)
+		return fmt.Errorf("unable to connect to database: %w", err)
 	}
 
-	p.pool = pool
-	p.connected = true
+	// 已连接时替换旧连接池, 旧连接池在进行中的调用结束后关闭
+	p.pool.replace(ctx, "postgresql", pool, func(context.Context) error {
+		pool.Close()
+		return nil
+	})
 	return nil
 }
 
+// Disconnect 等待进行中的调用结束后关闭连接池, 可以重复调用
 func (p *PostgreSQL) Disconnect(ctx context.Context) error {
-	if p.pool != nil {
-		p.pool.Close()
-		p.connected = false
-	}
-	return nil
+	return p.pool.disconnect(ctx)
 }
 
 func (p *PostgreSQL) Ping(ctx context.Context) error {
-	if p.pool == nil {
-		return fmt.Errorf("database not connected")
+	pool, release, err := p.pool.acquire()
+	if err != nil {
+		return err
 	}
-	return p.pool.Ping(ctx)
+	defer release()
+	return pool.Ping(ctx)
 }
 
 func (p *PostgreSQL) IsConnected() bool {
-	return p.connected
+	return p.pool.connected()
 }
 
+// Reconnect 按 RetryPolicy 建立新连接池并替换旧连接池, 见 SetRetryPolicy
 func (p *PostgreSQL) Reconnect(ctx context.Context) error {
-	p.Disconnect(ctx)
-
-	for i := 0; i < p.maxRetries; i++ {
-		err := p.Connect(ctx)
-		if err == nil {
-			return nil
-		}
-		time.Sleep(time.Second * time.Duration(i+1))
-	}
-	return fmt.Errorf("failed to reconnect after %d attempts", p.maxRetries)
+	return reconnect(ctx, "postgresql", p.RetryPolicy(), p.Connect)
 }
 
 // CRUD operations
+// data 和 update 可以是 map[string]interface{} 或带 db 标签的结构体, 结构体中 omitempty 的零值字段被跳过.
+// filter 还可以是 q.Query, sqlbuilder.Cond 或 sqlbuilder.Query, 结构体作为 filter 时只使用非零值字段
 func (p *PostgreSQL) Create(ctx context.Context, table string, data interface{}) error {
-	// 实现插入操作
-	return nil
+	columns, values, err := columnValues(data, modeWrite)
+	if err != nil {
+		return err
+	}
+	query, args, err := sqlbuilder.Insert(sqlbuilder.Postgres, table, columns, values)
+	if err != nil {
+		return err
+	}
+
+	db, release, err := p.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = db.Exec(ctx, query, args...)
+	return err
 }
 
 func (p *PostgreSQL) Read(ctx context.Context, table string, filter interface{}, result interface{}) error {
-	// 实现查询操作
+	q, err := sqlQuery(filter)
+	if err != nil {
+		return err
+	}
+	query, args, err := q.Select(sqlbuilder.Postgres, table).Limit(1).Build()
+	if err != nil {
+		return err
+	}
+
+	db, release, err := p.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	found, err := scanOne(rows, pgRowColumns(rows), result)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &NotFoundError{Collection: table}
+	}
 	return nil
 }
 
 func (p *PostgreSQL) Update(ctx context.Context, table string, filter interface{}, update interface{}) error {
-	// 实现更新操作
-	return nil
+	columns, values, err := columnValues(update, modeWrite)
+	if err != nil {
+		return err
+	}
+	where, err := sqlWhere(filter)
+	if err != nil {
+		return err
+	}
+	query, args, err := sqlbuilder.Update(sqlbuilder.Postgres, table, columns, values, where)
+	if err != nil {
+		return err
+	}
+
+	db, release, err := p.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = db.Exec(ctx, query, args...)
+	return err
 }
 
 func (p *PostgreSQL) Delete(ctx context.Context, table string, filter interface{}) error {
-	// 实现删除操作
-	return nil
+	where, err := sqlWhere(filter)
+	if err != nil {
+		return err
+	}
+	query, args, err := sqlbuilder.Delete(sqlbuilder.Postgres, table, where)
+	if err != nil {
+		return err
+	}
+
+	db, release, err := p.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = db.Exec(ctx, query, args...)
+	return err
 }
 
 func (p *PostgreSQL) List(ctx context.Context, table string, filter interface{}, results interface{}) error {
-	// 实现列表查询操作
-	return nil
+	q, err := sqlQuery(filter)
+	if err != nil {
+		return err
+	}
+	query, args, err := q.Select(sqlbuilder.Postgres, table).Build()
+	if err != nil {
+		return err
+	}
+
+	db, release, err := p.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return scanAll(rows, pgRowColumns(rows), results)
+}
+
+// Count 返回满足 filter 的记录数, 见 Counter
+func (p *PostgreSQL) Count(ctx context.Context, table string, filter interface{}) (int64, error) {
+	where, err := sqlWhere(filter)
+	if err != nil {
+		return 0, err
+	}
+	query, args, err := sqlbuilder.Count(sqlbuilder.Postgres, table, where)
+	if err != nil {
+		return 0, err
+	}
+
+	db, release, err := p.querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var n int64
+	err = db.QueryRow(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// ListAfter 按 key 做键集分页, 见 KeysetLister
+func (p *PostgreSQL) ListAfter(ctx context.Context, table string, filter interface{}, key string, after interface{}, limit int, results interface{}) error {
+	q, err := keysetQuery(filter, key, after, limit)
+	if err != nil {
+		return err
+	}
+	return p.List(ctx, table, q, results)
+}
+
+// WithTx 在事务中执行 fn, 见 Transactor. 序列化失败 (40001) 和死锁 (40P01) 时重试整个事务
+func (p *PostgreSQL) WithTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	o := newTxOptions(opts)
+
+	// 嵌套事务使用保存点
+	if outer, ok := txFromContext(ctx, p).(pgx.Tx); ok {
+		tx, err := outer.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		return runTx(contextWithTx(ctx, p, tx), fn,
+			func() error { return tx.Commit(ctx) },
+			func() error { return tx.Rollback(ctx) })
+	}
+
+	// 事务期间持有连接池, 重连不会关闭它
+	pool, release, err := p.pool.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	txOptions := pgx.TxOptions{IsoLevel: pgIsoLevel(o.Isolation)}
+	if o.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+	return retryTx(ctx, "postgresql", o.MaxRetries, pgRetryable, func() error {
+		tx, err := pool.BeginTx(ctx, txOptions)
+		if err != nil {
+			return err
+		}
+		return runTx(contextWithTx(ctx, p, tx), fn,
+			func() error { return tx.Commit(ctx) },
+			func() error { return tx.Rollback(ctx) })
+	})
+}
+
+// 辅助函数
+// connString 返回 DSN, 未配置时根据各字段构造 URI
+func (p *PostgreSQL) connString() string {
+	if p.config.DSN != "" {
+		return p.config.DSN
+	}
+	u := p.config.buildURL("postgresql")
+	u.Path = "/" + p.config.Database
+	return u.String()
+}
+
+// pgParams 将 TLS 相关选项转换为 pgx 连接参数
+func pgParams(opts *Options) url.Values {
+	params := url.Values{}
+	if opts.SSLMode != "" {
+		params.Set("sslmode", opts.SSLMode)
+	}
+	if opts.TLS.CAFile != "" {
+		params.Set("sslrootcert", opts.TLS.CAFile)
+	}
+	if opts.TLS.CertFile != "" {
+		params.Set("sslcert", opts.TLS.CertFile)
+		params.Set("sslkey", opts.TLS.KeyFile)
+	}
+	return params
+}
+
+// applyPgOptions 将连接池和超时选项应用到 pgxpool 配置
+func applyPgOptions(cfg *pgxpool.Config, opts *Options) {
+	if opts.MaxConns > 0 {
+		cfg.MaxConns = int32(opts.MaxConns)
+	}
+	if opts.MinConns > 0 {
+		cfg.MinConns = int32(opts.MinConns)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		cfg.MaxConnIdleTime = opts.ConnMaxIdleTime
+	}
+	if opts.ConnMaxLifetime > 0 {
+		cfg.MaxConnLifetime = opts.ConnMaxLifetime
+	}
+	if opts.ConnectTimeout > 0 {
+		cfg.ConnConfig.ConnectTimeout = opts.ConnectTimeout
+	}
+	if opts.AppName != "" {
+		cfg.ConnConfig.RuntimeParams["application_name"] = opts.AppName
+	}
+}
+
+// pgQuerier 是连接池和事务共有的查询方法
+type pgQuerier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// querier 返回 ctx 中的事务, 没有事务时返回连接池. 使用结束后调用 release
+func (p *PostgreSQL) querier(ctx context.Context) (q pgQuerier, release func(), err error) {
+	if tx, ok := txFromContext(ctx, p).(pgx.Tx); ok {
+		return tx, func() {}, nil
+	}
+	return p.pool.acquire()
+}
+
+func pgIsoLevel(level IsolationLevel) pgx.TxIsoLevel {
+	switch level {
+	case LevelReadUncommitted:
+		return pgx.ReadUncommitted
+	case LevelReadCommitted:
+		return pgx.ReadCommitted
+	case LevelRepeatableRead, LevelSnapshot:
+		// PostgreSQL 的 REPEATABLE READ 即快照隔离
+		return pgx.RepeatableRead
+	case LevelSerializable:
+		return pgx.Serializable
+	}
+	return ""
+}
+
+func pgRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
+
+func pgRowColumns(rows pgx.Rows) []string {
+	fields := rows.FieldDescriptions()
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = string(f.Name)
+	}
+	return columns
+}
+
+// pgConnRetryable 判断连接错误是否可以重试, 认证失败和数据库不存在不重试
+func pgConnRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return !strings.HasPrefix(pgErr.Code, "28") && !strings.HasPrefix(pgErr.Code, "3D")
+	}
+	return true
 }
@@ -0,0 +1,301 @@
+// Package q 定义与数据库无关的查询表达式, 由各驱动翻译为 SQL 或 BSON.
+//
+//	db.List(ctx, "users", q.Eq("status", "active").Gt("age", 18).Sort("-created"), &users)
+//
+// Query 是值类型, 每个方法返回新的 Query, 原值不变.
+//
+// NULL 的处理以 SQL 为准: Ne 和 NotIn 不匹配值为 NULL 的记录, MongoDB 中也不匹配字段不存在的文档.
+// Not 在两端不同: SQL 中 NOT 对 NULL 的比较结果仍为 NULL, 不匹配该行;
+// MongoDB 的 $nor 会匹配字段为 null 或不存在的文档. 需要一致结果时在 Not 中加上 NotNull 条件
+package q
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Op 比较运算符
+type Op string
+
+const (
+	OpEq      Op = "eq"
+	OpNe      Op = "ne"
+	OpGt      Op = "gt"
+	OpGte     Op = "gte"
+	OpLt      Op = "lt"
+	OpLte     Op = "lte"
+	OpIn      Op = "in"
+	OpNotIn   Op = "nin"
+	OpLike    Op = "like"
+	OpIsNull  Op = "null"
+	OpNotNull Op = "notnull"
+)
+
+// Expr 是过滤表达式: Cond, And, Or, Not 或 MatchAll
+type Expr interface {
+	expr()
+}
+
+// Cond 是单个字段的比较. In/NotIn 的 Value 是 []interface{}, IsNull/NotNull 没有 Value
+type Cond struct {
+	Field string
+	Op    Op
+	Value interface{}
+}
+
+// And 所有表达式都成立, 为空时匹配所有记录
+type And []Expr
+
+// Or 任一表达式成立, 为空时不匹配任何记录
+type Or []Expr
+
+// Not 表达式不成立, Expr 不能为 nil
+type Not struct {
+	Expr Expr
+}
+
+// MatchAll 匹配所有记录. Update 和 Delete 拒绝空条件, 需要影响所有记录时使用 All
+type MatchAll struct{}
+
+func (Cond) expr()     {}
+func (And) expr()      {}
+func (Or) expr()       {}
+func (Not) expr()      {}
+func (MatchAll) expr() {}
+
+// SortField 排序字段
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Query 组合过滤, 排序, 投影和分页
+type Query struct {
+	filter And
+	sort   []SortField
+	fields []string
+	limit  int
+	offset int
+}
+
+// New 返回匹配所有记录的空查询
+func New() Query {
+	return Query{}
+}
+
+// Filter 返回所有条件的 AND 组合, 没有条件时返回 nil
+func (q Query) Filter() Expr {
+	if len(q.filter) == 0 {
+		return nil
+	}
+	return q.filter
+}
+
+// SortFields 返回排序字段
+func (q Query) SortFields() []SortField {
+	return q.sort
+}
+
+// Fields 返回投影字段, 为空表示所有字段
+func (q Query) Fields() []string {
+	return q.fields
+}
+
+// LimitValue 返回最多返回的记录数, 0 表示不限制
+func (q Query) LimitValue() int {
+	return q.limit
+}
+
+// OffsetValue 返回跳过的记录数
+func (q Query) OffsetValue() int {
+	return q.offset
+}
+
+// Where 追加任意表达式
+func (q Query) Where(e Expr) Query {
+	filter := make(And, len(q.filter), len(q.filter)+1)
+	copy(filter, q.filter)
+	q.filter = append(filter, e)
+	return q
+}
+
+func (q Query) cond(field string, op Op, value interface{}) Query {
+	return q.Where(Cond{Field: field, Op: op, Value: value})
+}
+
+// Eq 字段等于 value, value 为 nil 时等同于 IsNull
+func (q Query) Eq(field string, value interface{}) Query {
+	if value == nil {
+		return q.IsNull(field)
+	}
+	return q.cond(field, OpEq, value)
+}
+
+// Ne 字段不等于 value 且不为 NULL, value 为 nil 时等同于 NotNull
+func (q Query) Ne(field string, value interface{}) Query {
+	if value == nil {
+		return q.NotNull(field)
+	}
+	return q.cond(field, OpNe, value)
+}
+
+// Gt 字段大于 value
+func (q Query) Gt(field string, value interface{}) Query { return q.cond(field, OpGt, value) }
+
+// Gte 字段大于等于 value
+func (q Query) Gte(field string, value interface{}) Query { return q.cond(field, OpGte, value) }
+
+// Lt 字段小于 value
+func (q Query) Lt(field string, value interface{}) Query { return q.cond(field, OpLt, value) }
+
+// Lte 字段小于等于 value
+func (q Query) Lte(field string, value interface{}) Query { return q.cond(field, OpLte, value) }
+
+// In 字段等于 values 中的任一值, 也可以传入单个切片
+func (q Query) In(field string, values ...interface{}) Query {
+	return q.cond(field, OpIn, flatten(values))
+}
+
+// NotIn 字段不等于 values 中的任何值且不为 NULL, 也可以传入单个切片
+func (q Query) NotIn(field string, values ...interface{}) Query {
+	return q.cond(field, OpNotIn, flatten(values))
+}
+
+// Like 按 SQL LIKE 模式匹配, % 匹配任意字符串, _ 匹配单个字符.
+// 大小写是否敏感取决于数据库 (如 SQL Server 的排序规则), MongoDB 区分大小写
+func (q Query) Like(field string, pattern string) Query {
+	return q.cond(field, OpLike, pattern)
+}
+
+// IsNull 字段为 NULL, MongoDB 中也匹配字段不存在的文档
+func (q Query) IsNull(field string) Query { return q.cond(field, OpIsNull, nil) }
+
+// NotNull 字段不为 NULL
+func (q Query) NotNull(field string) Query { return q.cond(field, OpNotNull, nil) }
+
+// Or 追加一个 OR 组, 每个子查询的条件先做 AND 再做 OR.
+// 没有子查询时不匹配任何记录, 任一子查询没有条件时 OR 恒真, 不追加条件
+func (q Query) Or(alternatives ...Query) Query {
+	or := make(Or, 0, len(alternatives))
+	for _, alt := range alternatives {
+		f := alt.Filter()
+		if f == nil {
+			return q
+		}
+		or = append(or, f)
+	}
+	return q.Where(or)
+}
+
+// Not 追加子查询条件的否定
+func (q Query) Not(sub Query) Query {
+	if f := sub.Filter(); f != nil {
+		return q.Where(Not{f})
+	}
+	return q
+}
+
+// Sort 追加排序字段, "-name" 表示降序, "name" 或 "+name" 表示升序
+func (q Query) Sort(fields ...string) Query {
+	sort := make([]SortField, len(q.sort), len(q.sort)+len(fields))
+	copy(sort, q.sort)
+	for _, f := range fields {
+		switch {
+		case strings.HasPrefix(f, "-"):
+			sort = append(sort, SortField{Field: f[1:], Desc: true})
+		case strings.HasPrefix(f, "+"):
+			sort = append(sort, SortField{Field: f[1:]})
+		default:
+			sort = append(sort, SortField{Field: f})
+		}
+	}
+	q.sort = sort
+	return q
+}
+
+// Select 设置投影字段
+func (q Query) Select(fields ...string) Query {
+	q.fields = append([]string(nil), fields...)
+	return q
+}
+
+// Limit 设置最多返回的记录数, 0 表示不限制
+func (q Query) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+// Offset 设置跳过的记录数
+func (q Query) Offset(n int) Query {
+	q.offset = n
+	return q
+}
+
+// 包级函数从空查询开始构造
+
+// Where 返回包含表达式 e 的查询
+func Where(e Expr) Query { return New().Where(e) }
+
+// Eq 见 Query.Eq
+func Eq(field string, value interface{}) Query { return New().Eq(field, value) }
+
+// Ne 见 Query.Ne
+func Ne(field string, value interface{}) Query { return New().Ne(field, value) }
+
+// Gt 见 Query.Gt
+func Gt(field string, value interface{}) Query { return New().Gt(field, value) }
+
+// Gte 见 Query.Gte
+func Gte(field string, value interface{}) Query { return New().Gte(field, value) }
+
+// Lt 见 Query.Lt
+func Lt(field string, value interface{}) Query { return New().Lt(field, value) }
+
+// Lte 见 Query.Lte
+func Lte(field string, value interface{}) Query { return New().Lte(field, value) }
+
+// In 见 Query.In
+func In(field string, values ...interface{}) Query { return New().In(field, values...) }
+
+// NotIn 见 Query.NotIn
+func NotIn(field string, values ...interface{}) Query { return New().NotIn(field, values...) }
+
+// Like 见 Query.Like
+func Like(field string, pattern string) Query { return New().Like(field, pattern) }
+
+// IsNull 见 Query.IsNull
+func IsNull(field string) Query { return New().IsNull(field) }
+
+// NotNull 见 Query.NotNull
+func NotNull(field string) Query { return New().NotNull(field) }
+
+// All 返回明确匹配所有记录的查询, 用于 Update 和 Delete 影响所有记录
+func All() Query { return New().Where(MatchAll{}) }
+
+// Any 返回任一子查询成立的查询
+func Any(alternatives ...Query) Query { return New().Or(alternatives...) }
+
+// Sort 见 Query.Sort
+func Sort(fields ...string) Query { return New().Sort(fields...) }
+
+// Select 见 Query.Select
+func Select(fields ...string) Query { return New().Select(fields...) }
+
+// flatten 展开作为唯一参数传入的切片, []byte 作为单个值
+func flatten(values []interface{}) []interface{} {
+	if len(values) != 1 || values[0] == nil {
+		return values
+	}
+	if _, ok := values[0].([]byte); ok {
+		return values
+	}
+	rv := reflect.ValueOf(values[0])
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return values
+	}
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Dankko0w0/gospike/db/retry"
+)
+
+// retrier 为驱动提供可配置的重试策略
+type retrier struct {
+	mu       sync.RWMutex
+	policy   retry.Policy
+	classify func(error) bool
+}
+
+func newRetrier(classify func(error) bool) retrier {
+	return retrier{policy: retry.Default(), classify: classify}
+}
+
+// SetRetryPolicy 设置 Reconnect 使用的重试策略, 策略未设置 Retryable 时使用驱动的错误分类
+func (r *retrier) SetRetryPolicy(policy retry.Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = policy
+}
+
+// RetryPolicy 返回带驱动连接错误分类的重试策略. 分类只区分连接错误是否值得重试,
+// 唯一约束冲突等语句错误也会被视为可重试, 因此只用它包装 Ping 这类幂等的连接检查, 不要包装写操作:
+//
+//	err := pg.RetryPolicy().Do(ctx, pg.Ping)
+func (r *retrier) RetryPolicy() retry.Policy {
+	r.mu.RLock()
+	policy := r.policy
+	r.mu.RUnlock()
+
+	if policy.Retryable == nil {
+		classify := r.classify
+		policy.Retryable = func(err error) bool {
+			return !errors.Is(err, ErrInvalidOptions) && (classify == nil || classify(err))
+		}
+	}
+	return policy
+}
+
+// reconnect 按策略重新连接, 返回包装了最后一次错误的结果.
+// connect 成功后替换旧客户端, 失败期间调用继续使用旧客户端
+func reconnect(ctx context.Context, driver string, policy retry.Policy, connect func(ctx context.Context) error) error {
+	log := dbLogger(ctx, driver)
+
+	onRetry := policy.OnRetry
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		log.Warn().Err(err).Int("attempt", attempt).Dur("delay", delay).Msg("reconnect attempt failed")
+		if onRetry != nil {
+			onRetry(attempt, err, delay)
+		}
+	}
+
+	if err := policy.Do(ctx, connect); err != nil {
+		return fmt.Errorf("failed to reconnect: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Dankko0w0/gospike/confManager"
+)
+
+// DefaultConfigKey 是命名连接在配置文件中的默认位置, 如 databases.main.driver
+const DefaultConfigKey = "databases"
+
+// Driver 根据配置创建数据库实例
+type Driver func(config *Config) DBInterface
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{
+		"postgres":   func(c *Config) DBInterface { return NewPostgreSQL(c) },
+		"postgresql": func(c *Config) DBInterface { return NewPostgreSQL(c) },
+		"sqlserver":  func(c *Config) DBInterface { return NewSQLServer(c) },
+		"mssql":      func(c *Config) DBInterface { return NewSQLServer(c) },
+		"mongodb":    func(c *Config) DBInterface { return NewMongoDB(c) },
+		"mongo":      func(c *Config) DBInterface { return NewMongoDB(c) },
+		"redis":      func(c *Config) DBInterface { return NewRedis(c) },
+		"etcd":       func(c *Config) DBInterface { return NewEtcd(c) },
+	}
+)
+
+// Register 注册数据库驱动, 第三方驱动通常在 init 中调用.
+// 重复注册同名驱动或传入 nil 会 panic
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if driver == nil {
+		panic("db: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("db: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers 返回已注册的驱动名称
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// connectionConfig 是配置文件中单个命名连接的结构
+type connectionConfig struct {
+	Driver string `mapstructure:"driver"`
+	Config `mapstructure:",squash"`
+}
+
+// Registry 管理按名称打开的数据库连接
+type Registry struct {
+	configKey string
+
+	mu    sync.Mutex
+	conns map[string]DBInterface
+}
+
+// NewRegistry 创建从 configKey 下读取连接配置的 Registry
+func NewRegistry(configKey string) *Registry {
+	return &Registry{
+		configKey: configKey,
+		conns:     make(map[string]DBInterface),
+	}
+}
+
+var defaultRegistry = NewRegistry(DefaultConfigKey)
+
+// DefaultRegistry 返回包级函数使用的 Registry
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Open 返回名为 name 的连接, 首次调用时根据配置创建并连接.
+// 连接时不持有锁, 慢速数据库不会阻塞其他连接; 并发打开同一连接时保留先完成的一个
+func (r *Registry) Open(ctx context.Context, name string) (DBInterface, error) {
+	if conn, ok := r.Get(name); ok {
+		return conn, nil
+	}
+
+	config, driverName, err := r.load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	driversMu.RLock()
+	driver, ok := drivers[driverName]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("database %q: unknown driver %q", name, driverName)
+	}
+
+	conn := driver(config)
+	if err := conn.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("database %q (%s): %w", name, config.Redacted(), err)
+	}
+
+	log := dbLogger(ctx, driverName)
+	r.mu.Lock()
+	if existing, ok := r.conns[name]; ok {
+		r.mu.Unlock()
+		if err := conn.Disconnect(ctx); err != nil {
+			log.Warn().Err(err).Str("name", name).Msg("failed to close duplicate connection")
+		}
+		return existing, nil
+	}
+	r.conns[name] = conn
+	r.mu.Unlock()
+
+	log.Info().Str("name", name).Str("dsn", config.Redacted()).Msg("database connected")
+	return conn, nil
+}
+
+// load 从 confManager 读取名为 name 的连接配置
+func (r *Registry) load(name string) (*Config, string, error) {
+	if !confManager.ConfInitialized {
+		return nil, "", fmt.Errorf("config manager is not initialized")
+	}
+
+	key := r.configKey + "." + name
+	if !confManager.IsSet(key) {
+		return nil, "", fmt.Errorf("database %q is not configured under %q", name, key)
+	}
+
+	var cc connectionConfig
+	if err := confManager.UnmarshalKey(key, &cc); err != nil {
+		return nil, "", fmt.Errorf("failed to decode database config %q: %w", key, err)
+	}
+	if cc.Driver == "" {
+		return nil, "", fmt.Errorf("database %q: driver is required", name)
+	}
+	return &cc.Config, cc.Driver, nil
+}
+
+// Add 注册一个已创建的连接, 由 Registry 负责关闭
+func (r *Registry) Add(name string, conn DBInterface) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.conns[name]; ok {
+		return fmt.Errorf("database %q is already registered", name)
+	}
+	r.conns[name] = conn
+	return nil
+}
+
+// Get 返回已打开的连接
+func (r *Registry) Get(name string) (DBInterface, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conn, ok := r.conns[name]
+	return conn, ok
+}
+
+// Names 返回已打开连接的名称
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.conns))
+	for name := range r.conns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close 断开所有连接并清空 Registry, 返回所有断开失败的错误
+func (r *Registry) Close(ctx context.Context) error {
+	r.mu.Lock()
+	conns := r.conns
+	r.conns = make(map[string]DBInterface)
+	r.mu.Unlock()
+
+	var errs []error
+	for name, conn := range conns {
+		if err := conn.Disconnect(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("database %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Open 使用默认 Registry 打开名为 name 的连接
+func Open(ctx context.Context, name string) (DBInterface, error) {
+	return defaultRegistry.Open(ctx, name)
+}
+
+// OpenAs 打开名为 name 的连接并断言为具体驱动类型, 如 OpenAs[*PostgreSQL]
+func OpenAs[T DBInterface](ctx context.Context, name string) (T, error) {
+	var zero T
+	conn, err := defaultRegistry.Open(ctx, name)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := conn.(T)
+	if !ok {
+		return zero, fmt.Errorf("database %q is %T, not %T", name, conn, zero)
+	}
+	return typed, nil
+}
+
+// Close 关闭默认 Registry 中的所有连接
+func Close(ctx context.Context) error {
+	return defaultRegistry.Close(ctx)
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Dankko0w0/gospike/confManager"
+)
+
+// blockingConn is a DBInterface whose Connect waits until release is closed
+type blockingConn struct {
+	release     chan struct{}
+	connects    *atomic.Int32
+	disconnects *atomic.Int32
+}
+
+func (c *blockingConn) Connect(ctx context.Context) error {
+	c.connects.Add(1)
+	<-c.release
+	return nil
+}
+
+func (c *blockingConn) Disconnect(ctx context.Context) error {
+	c.disconnects.Add(1)
+	return nil
+}
+
+func (c *blockingConn) Ping(ctx context.Context) error      { return nil }
+func (c *blockingConn) IsConnected() bool                   { return true }
+func (c *blockingConn) Reconnect(ctx context.Context) error { return nil }
+
+func initTestConfig(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	config := "databases:\n  slow:\n    driver: blocking-test\n"
+	if err := os.WriteFile(filepath.Join(dir, "registry_test.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := confManager.InitConfig(dir, "registry_test", "yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blockingDriver is registered once and creates connections from newBlocking
+var (
+	blockingOnce sync.Once
+	newBlocking  func() DBInterface
+)
+
+func TestRegistryOpenDoesNotBlockOtherConnections(t *testing.T) {
+	initTestConfig(t)
+
+	release := make(chan struct{})
+	var connects, disconnects atomic.Int32
+	newBlocking = func() DBInterface {
+		return &blockingConn{release: release, connects: &connects, disconnects: &disconnects}
+	}
+	blockingOnce.Do(func() {
+		Register("blocking-test", func(*Config) DBInterface { return newBlocking() })
+	})
+
+	r := NewRegistry(DefaultConfigKey)
+	results := make([]DBInterface, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := r.Open(context.Background(), "slow")
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = conn
+		}(i)
+	}
+
+	for connects.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Add("fast", &blockingConn{connects: &connects, disconnects: &disconnects})
+		r.Names()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Registry was locked while a connection was dialing")
+	}
+
+	close(release)
+	wg.Wait()
+	if results[0] != results[1] {
+		t.Error("concurrent Open returned different connections")
+	}
+	if n := disconnects.Load(); n != 1 {
+		t.Errorf("duplicate connection disconnected %d times, want 1", n)
+	}
+}
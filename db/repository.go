@@ -0,0 +1,325 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Tabler 由实体实现以指定表名或集合名
+type Tabler interface {
+	TableName() string
+}
+
+// Entity 是从结构体标签解析出的实体元数据
+type Entity struct {
+	// Table 是表名或集合名, 来自 TableName 方法, 否则为类型名的 snake_case
+	Table string
+	// Key 是主键列名, 来自带 pk 选项的 db 标签, 如 db:"id,pk", 否则为 id 列
+	Key string
+	// DocumentKey 是主键在 MongoDB 文档中的字段名, 来自主键字段的 bson 标签 (如 bson:"_id"),
+	// 没有 bson 标签时为字段名的小写, 与 mongo-driver 的默认规则相同
+	DocumentKey string
+
+	keyIndex []int
+	keyType  reflect.Type
+}
+
+var entityCache sync.Map // reflect.Type -> *Entity
+
+// entityOf 解析结构体类型 t 的实体元数据
+func entityOf(t reflect.Type) (*Entity, error) {
+	if e, ok := entityCache.Load(t); ok {
+		return e.(*Entity), nil
+	}
+	if !isStructTarget(t) {
+		return nil, fmt.Errorf("entity type %s is not a struct", t)
+	}
+
+	e := &Entity{Table: toSnakeCase(t.Name())}
+	if tabler, ok := reflect.New(t).Interface().(Tabler); ok {
+		e.Table = tabler.TableName()
+	}
+
+	info := getStructInfo(t)
+	key := -1
+	for i, f := range info.fields {
+		sf := t.FieldByIndex(f.index)
+		_, opts, _ := strings.Cut(sf.Tag.Get("db"), ",")
+		if hasTagOption(opts, "pk") {
+			key = i
+			break
+		}
+	}
+	if key < 0 {
+		i, ok := info.byColumn["id"]
+		if !ok {
+			return nil, fmt.Errorf("entity %s has no primary key, tag one field with db:\"name,pk\"", t)
+		}
+		key = i
+	}
+	f := info.fields[key]
+	sf := t.FieldByIndex(f.index)
+	e.Key = f.column
+	e.DocumentKey = bsonName(sf)
+	e.keyIndex = f.index
+	e.keyType = sf.Type
+
+	actual, _ := entityCache.LoadOrStore(t, e)
+	return actual.(*Entity), nil
+}
+
+// bsonName 返回字段在 BSON 文档中的名称
+func bsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("bson"), ",")
+	if name == "" || name == "-" {
+		return strings.ToLower(sf.Name)
+	}
+	return name
+}
+
+func hasTagOption(opts, name string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if strings.TrimSpace(o) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyNamer 由不按 db 标签列存储实体的驱动实现, 如按 bson 标签存储的 MongoDB.
+// KeyName 返回实体主键在驱动中的字段名. 包装这类驱动的类型需要转发 KeyName,
+// 否则在 NewRepository 中使用 WithKeyName
+type KeyNamer interface {
+	KeyName(e *Entity) string
+}
+
+// RepositoryOption 修改 Repository 配置
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	keyName string
+}
+
+// WithKeyName 指定主键在驱动中的字段名, 效果与驱动实现 KeyNamer 相同, 优先于 KeyNamer
+func WithKeyName(name string) RepositoryOption {
+	return func(o *repositoryOptions) { o.keyName = name }
+}
+
+// Repository 是基于 DataOperation 的类型化数据访问层, T 必须是结构体.
+// filter 参数原样传给驱动, 因此可以使用驱动支持的任何 filter 形式.
+//
+// 驱动按 db 标签存储实体时 (SQL 驱动), Insert 和 Update 只传递列值: Update 不写入主键列,
+// Insert 在主键为零值时不写入主键列, 由数据库生成 (如 IDENTITY 或 SERIAL).
+// 驱动实现 KeyNamer 或使用 WithKeyName 时实体原样传给驱动, 由驱动自身的标签决定写入哪些字段
+type Repository[T any] struct {
+	op      DataOperation
+	entity  *Entity
+	key     string // 驱动中的主键字段名
+	columns bool   // 是否按 db 标签的列传递实体
+}
+
+// NewRepository 创建实体 T 的 Repository
+func NewRepository[T any](op DataOperation, opts ...RepositoryOption) (*Repository[T], error) {
+	e, err := entityOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	var o repositoryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := &Repository[T]{op: op, entity: e, key: e.Key, columns: true}
+	if namer, ok := op.(KeyNamer); ok {
+		r.key, r.columns = namer.KeyName(e), false
+	}
+	if o.keyName != "" {
+		r.key, r.columns = o.keyName, false
+	}
+	return r, nil
+}
+
+// Entity 返回实体元数据
+func (r *Repository[T]) Entity() *Entity {
+	return r.entity
+}
+
+func (r *Repository[T]) keyFilter(id interface{}) map[string]interface{} {
+	return map[string]interface{}{r.key: id}
+}
+
+// keyOf 返回实体的主键值
+func (r *Repository[T]) keyOf(entity *T) (interface{}, error) {
+	v, ok := fieldByIndexNoAlloc(reflect.ValueOf(entity).Elem(), r.entity.keyIndex)
+	if !ok || v.IsZero() {
+		return nil, fmt.Errorf("%s: primary key %s is not set", r.entity.Table, r.key)
+	}
+	return v.Interface(), nil
+}
+
+// Get 按主键读取实体, 不存在时返回的错误满足 errors.Is(err, ErrNotFound)
+func (r *Repository[T]) Get(ctx context.Context, id interface{}) (*T, error) {
+	var entity T
+	if err := r.op.Read(ctx, r.entity.Table, r.keyFilter(id), &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// Find 返回满足 filter 的所有实体
+func (r *Repository[T]) Find(ctx context.Context, filter interface{}) ([]T, error) {
+	var results []T
+	if err := r.op.List(ctx, r.entity.Table, filter, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Insert 插入实体. SQL 驱动在主键为零值时不写入主键列
+func (r *Repository[T]) Insert(ctx context.Context, entity *T) error {
+	if !r.columns {
+		return r.op.Create(ctx, r.entity.Table, entity)
+	}
+	key, _ := fieldByIndexNoAlloc(reflect.ValueOf(entity).Elem(), r.entity.keyIndex)
+	data, err := r.columnsOf(entity, key.IsValid() && key.IsZero())
+	if err != nil {
+		return err
+	}
+	return r.op.Create(ctx, r.entity.Table, data)
+}
+
+// Update 按主键更新实体. 写入规则与 Insert 相同, 带 omitempty 的零值字段 (SQL 看 db 标签, MongoDB 看 bson 标签) 不会被写入;
+// 需要将这些字段清零时直接调用 DataOperation.Update 并传入包含零值的 map
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	id, err := r.keyOf(entity)
+	if err != nil {
+		return err
+	}
+	if !r.columns {
+		return r.op.Update(ctx, r.entity.Table, r.keyFilter(id), entity)
+	}
+	// 主键只出现在条件中, SQL Server 不允许更新 IDENTITY 列
+	data, err := r.columnsOf(entity, true)
+	if err != nil {
+		return err
+	}
+	return r.op.Update(ctx, r.entity.Table, r.keyFilter(id), data)
+}
+
+// columnsOf 返回实体写入时的列值, skipKey 为 true 时去掉主键列
+func (r *Repository[T]) columnsOf(entity *T, skipKey bool) (columnSet, error) {
+	columns, values, err := columnValues(entity, modeWrite)
+	if err != nil {
+		return columnSet{}, err
+	}
+	var set columnSet
+	for i, col := range columns {
+		if skipKey && col == r.entity.Key {
+			continue
+		}
+		set.columns = append(set.columns, col)
+		set.values = append(set.values, values[i])
+	}
+	return set, nil
+}
+
+// Delete 按主键删除实体
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	return r.op.Delete(ctx, r.entity.Table, r.keyFilter(id))
+}
+
+// Count 返回满足 filter 的记录数. 驱动未实现 Counter 时读取全部记录计数
+func (r *Repository[T]) Count(ctx context.Context, filter interface{}) (int64, error) {
+	if c, ok := r.op.(Counter); ok {
+		return c.Count(ctx, r.entity.Table, filter)
+	}
+	results, err := r.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(results)), nil
+}
+
+// Exists 判断是否存在满足 filter 的记录
+func (r *Repository[T]) Exists(ctx context.Context, filter interface{}) (bool, error) {
+	var entity T
+	err := r.op.Read(ctx, r.entity.Table, filter, &entity)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Page 是键集分页的一页结果
+type Page[T any] struct {
+	Items []T
+	// Next 是下一页的游标, 没有更多记录时为空
+	Next string
+}
+
+// Page 按主键升序返回 cursor 之后最多 limit 条满足 filter 的记录, cursor 为空时从第一条开始.
+// 驱动需要实现 KeysetLister
+func (r *Repository[T]) Page(ctx context.Context, filter interface{}, cursor string, limit int) (*Page[T], error) {
+	lister, ok := r.op.(KeysetLister)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support keyset pagination", r.op)
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("page limit must be positive")
+	}
+
+	var after interface{}
+	if cursor != "" {
+		key, err := r.decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = key
+	}
+
+	// 多取一条判断是否还有下一页
+	var items []T
+	if err := lister.ListAfter(ctx, r.entity.Table, filter, r.key, after, limit+1, &items); err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		next, err := r.encodeCursor(&page.Items[limit-1])
+		if err != nil {
+			return nil, err
+		}
+		page.Next = next
+	}
+	return page, nil
+}
+
+// encodeCursor 将实体主键编码为不透明的游标
+func (r *Repository[T]) encodeCursor(entity *T) (string, error) {
+	v, _ := fieldByIndexNoAlloc(reflect.ValueOf(entity).Elem(), r.entity.keyIndex)
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 将游标解码为主键字段类型的值
+func (r *Repository[T]) decodeCursor(cursor string) (interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	key := reflect.New(r.entity.keyType)
+	if err := json.Unmarshal(data, key.Interface()); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return key.Elem().Interface(), nil
+}
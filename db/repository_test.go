@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/db/sqlbuilder"
+)
+
+type repoUser struct {
+	ID   int64  `db:"id,pk" bson:"_id"`
+	Name string `db:"name" bson:"name"`
+}
+
+// recordingOp 记录 Repository 传给驱动的 filter
+type recordingOp struct {
+	filters []interface{}
+}
+
+func (o *recordingOp) Create(ctx context.Context, collection string, data interface{}) error {
+	return nil
+}
+
+func (o *recordingOp) Read(ctx context.Context, collection string, filter interface{}, result interface{}) error {
+	o.filters = append(o.filters, filter)
+	return nil
+}
+
+func (o *recordingOp) Update(ctx context.Context, collection string, filter interface{}, update interface{}) error {
+	o.filters = append(o.filters, filter)
+	return nil
+}
+
+func (o *recordingOp) Delete(ctx context.Context, collection string, filter interface{}) error {
+	o.filters = append(o.filters, filter)
+	return nil
+}
+
+func (o *recordingOp) List(ctx context.Context, collection string, filter interface{}, results interface{}) error {
+	return nil
+}
+
+func (o *recordingOp) recorded() []interface{} { return o.filters }
+
+// recordingDocOp 模拟按 BSON 字段名存储的驱动
+type recordingDocOp struct {
+	recordingOp
+}
+
+func (o *recordingDocOp) KeyName(e *Entity) string { return e.DocumentKey }
+
+func TestRepositoryKeyPerBackend(t *testing.T) {
+	tests := []struct {
+		name string
+		op   interface {
+			DataOperation
+			recorded() []interface{}
+		}
+		key string
+	}{
+		{"sql", &recordingOp{}, "id"},
+		{"document", &recordingDocOp{}, "_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := NewRepository[repoUser](tt.op)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			if _, err := repo.Get(ctx, int64(7)); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.Update(ctx, &repoUser{ID: 7, Name: "a"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.Delete(ctx, int64(7)); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]interface{}{tt.key: int64(7)}
+			for i, f := range tt.op.recorded() {
+				if !reflect.DeepEqual(f, want) {
+					t.Errorf("call %d: filter %v, want %v", i, f, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEntityDocumentKey(t *testing.T) {
+	type noTag struct {
+		UserID int64 `db:"user_id,pk"`
+	}
+	e, err := entityOf(reflect.TypeOf(noTag{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Key != "user_id" || e.DocumentKey != "userid" {
+		t.Errorf("Key = %q, DocumentKey = %q", e.Key, e.DocumentKey)
+	}
+}
+
+type identityUser struct {
+	ID   int64  `db:"id,pk"`
+	Name string `db:"name"`
+}
+
+func TestRepositorySQLServerStatements(t *testing.T) {
+	ctx := context.Background()
+	s, fake := newFakeSQLServer(t)
+	repo, err := NewRepository[identityUser](s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"insert new entity", func() error { return repo.Insert(ctx, &identityUser{Name: "a"}) },
+			`INSERT INTO [identity_user] ([name]) VALUES (@p1) [a]`},
+		{"insert with key", func() error { return repo.Insert(ctx, &identityUser{ID: 5, Name: "a"}) },
+			`INSERT INTO [identity_user] ([id], [name]) VALUES (@p1, @p2) [5 a]`},
+		{"update", func() error { return repo.Update(ctx, &identityUser{ID: 5, Name: "b"}) },
+			`UPDATE [identity_user] SET [name] = @p1 WHERE [id] = @p2 [b 5]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatal(err)
+			}
+			if got := fake.log(); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("statements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryPostgresStatements(t *testing.T) {
+	ctx := context.Background()
+	op := &capturingOp{}
+	repo, err := NewRepository[identityUser](op)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Insert(ctx, &identityUser{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	columns, values, err := columnValues(op.data, modeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, _, err := sqlbuilder.Insert(sqlbuilder.Postgres, "identity_user", columns, values)
+	if want := `INSERT INTO "identity_user" ("name") VALUES ($1)`; err != nil || query != want {
+		t.Errorf("insert = %q, %v, want %q", query, err, want)
+	}
+
+	if err := repo.Update(ctx, &identityUser{ID: 5, Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	columns, values, err = columnValues(op.data, modeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, err := sqlWhere(op.filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, _, err = sqlbuilder.Update(sqlbuilder.Postgres, "identity_user", columns, values, where)
+	if want := `UPDATE "identity_user" SET "name" = $1 WHERE "id" = $2`; err != nil || query != want {
+		t.Errorf("update = %q, %v, want %q", query, err, want)
+	}
+}
+
+// capturingOp 记录最后一次写入的数据和 filter
+type capturingOp struct {
+	recordingOp
+	data   interface{}
+	filter interface{}
+}
+
+func (o *capturingOp) Create(ctx context.Context, collection string, data interface{}) error {
+	o.data = data
+	return nil
+}
+
+func (o *capturingOp) Update(ctx context.Context, collection string, filter interface{}, update interface{}) error {
+	o.data, o.filter = update, filter
+	return nil
+}
+
+// wrappedMongo 模拟包装了 MongoDB 但没有转发 KeyName 的驱动
+type wrappedMongo struct {
+	capturingOp
+}
+
+func TestRepositoryWithKeyName(t *testing.T) {
+	op := &wrappedMongo{}
+	repo, err := NewRepository[repoUser](op, WithKeyName("_id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := &repoUser{ID: 7, Name: "a"}
+	if err := repo.Update(context.Background(), entity); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"_id": int64(7)}; !reflect.DeepEqual(op.filter, want) {
+		t.Errorf("filter = %v, want %v", op.filter, want)
+	}
+	if op.data != entity {
+		t.Errorf("document stores should receive the entity unchanged, got %#v", op.data)
+	}
+}
@@ -0,0 +1,154 @@
+// Package retry 实现带指数退避和随机抖动的重试策略
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy 描述重试策略, 零值表示不重试
+type Policy struct {
+	// MaxAttempts 是最多尝试次数 (包括第一次), 0 表示只受 MaxElapsed 限制,
+	// 两者都为 0 时只尝试一次
+	MaxAttempts int
+	// InitialInterval 是第一次重试前的等待时间
+	InitialInterval time.Duration
+	// MaxInterval 是单次等待的上限, 0 表示不限制
+	MaxInterval time.Duration
+	// Multiplier 是每次重试后等待时间的倍数, 小于 1 时按 1 处理
+	Multiplier float64
+	// Jitter 是随机抖动比例, 0.2 表示在 ±20% 内随机
+	Jitter float64
+	// MaxElapsed 是从第一次尝试开始的总时长上限, 0 表示不限制
+	MaxElapsed time.Duration
+
+	// Retryable 判断错误是否可以重试, nil 表示所有错误都可以重试
+	Retryable func(error) bool
+	// OnRetry 在每次等待前调用, 可用于记录日志
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Default 返回默认策略: 最多 3 次, 从 1s 开始翻倍, 上限 30s, ±20% 抖动
+func Default() Policy {
+	return Policy{
+		MaxAttempts:     3,
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+	}
+}
+
+// WithRetryable 返回使用 retryable 判断错误的策略副本
+func (p Policy) WithRetryable(retryable func(error) bool) Policy {
+	p.Retryable = retryable
+	return p
+}
+
+// Error 是重试耗尽后返回的错误, 包装最后一次的错误
+type Error struct {
+	Attempts int
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装不应重试的错误, Do 遇到时立即返回原错误
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// Do 按策略执行 fn, 直到成功, 遇到不可重试的错误, 次数或时间耗尽, 或 ctx 结束.
+// 第一次就失败且不可重试时返回原错误, 否则返回 *Error
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 && p.MaxElapsed <= 0 {
+		maxAttempts = 1
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if !p.retryable(err) {
+			return err
+		}
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			return &Error{Attempts: attempt, Err: err}
+		}
+
+		delay := p.Backoff(attempt)
+		if p.MaxElapsed > 0 && time.Since(start)+delay > p.MaxElapsed {
+			return &Error{Attempts: attempt, Err: err}
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &Error{Attempts: attempt, Err: errors.Join(err, ctx.Err())}
+		case <-timer.C:
+		}
+	}
+}
+
+func (p Policy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// Backoff 返回第 attempt 次失败后的等待时间, attempt 从 1 开始
+func (p Policy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+// Do 使用策略 p 执行 fn, 等同于 p.Do
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	return p.Do(ctx, fn)
+}
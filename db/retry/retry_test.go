@@ -0,0 +1,163 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTemporary = errors.New("temporary")
+
+func TestBackoffGrowth(t *testing.T) {
+	p := Policy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{10, time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	// Multiplier 小于 1 按 1 处理
+	constant := Policy{InitialInterval: 50 * time.Millisecond, Multiplier: 0.5}
+	if got := constant.Backoff(5); got != 50*time.Millisecond {
+		t.Errorf("Backoff with multiplier < 1 = %v, want 50ms", got)
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	p := Policy{InitialInterval: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.2}
+	for attempt := 1; attempt <= 3; attempt++ {
+		base := float64(100*time.Millisecond) * float64(int(1)<<(attempt-1))
+		low, high := time.Duration(base*0.8), time.Duration(base*1.2)
+		var varied bool
+		first := p.Backoff(attempt)
+		for i := 0; i < 1000; i++ {
+			d := p.Backoff(attempt)
+			if d < low || d > high {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", attempt, d, low, high)
+			}
+			varied = varied || d != first
+		}
+		if !varied {
+			t.Errorf("Backoff(%d) returned %v every time, jitter not applied", attempt, first)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	fast := Policy{MaxAttempts: 3, InitialInterval: time.Millisecond, Multiplier: 2}
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		name         string
+		policy       Policy
+		errs         []error // fn 依次返回的错误, 用完后返回 nil
+		wantAttempts int
+		wantErr      error
+		wantExhaust  bool // 是否返回 *Error
+	}{
+		{"success", fast, nil, 1, nil, false},
+		{"retry then success", fast, []error{errTemporary, errTemporary}, 3, nil, false},
+		{"exhausted", fast, []error{errTemporary, errTemporary, errTemporary, errTemporary}, 3, errTemporary, true},
+		{"zero policy tries once", Policy{}, []error{errTemporary}, 1, errTemporary, true},
+		{"permanent", fast, []error{Permanent(errFatal)}, 1, errFatal, false},
+		{"not retryable", fast.WithRetryable(func(err error) bool { return err != errFatal }),
+			[]error{errTemporary, errFatal}, 2, errFatal, false},
+		{"context error not retried", fast, []error{context.DeadlineExceeded}, 1, context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			var retries []int
+			tt.policy.OnRetry = func(attempt int, err error, delay time.Duration) { retries = append(retries, attempt) }
+			err := tt.policy.Do(context.Background(), func(context.Context) error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			var exhausted *Error
+			if got := errors.As(err, &exhausted); got != tt.wantExhaust {
+				t.Errorf("err %v: *Error = %v, want %v", err, got, tt.wantExhaust)
+			}
+			if tt.name == "permanent" && err != errFatal {
+				t.Errorf("Permanent should return the original error, got %#v", err)
+			}
+			if len(retries) != attempts-1 {
+				t.Errorf("OnRetry called %d times for %d attempts", len(retries), attempts)
+			}
+		})
+	}
+}
+
+func TestDoStopsAtMaxElapsed(t *testing.T) {
+	p := Policy{InitialInterval: 20 * time.Millisecond, Multiplier: 1, MaxElapsed: 70 * time.Millisecond}
+	attempts := 0
+	start := time.Now()
+	err := p.Do(context.Background(), func(context.Context) error {
+		attempts++
+		return errTemporary
+	})
+
+	var exhausted *Error
+	if !errors.As(err, &exhausted) || !errors.Is(err, errTemporary) {
+		t.Fatalf("err = %v, want *Error wrapping errTemporary", err)
+	}
+	if elapsed := time.Since(start); elapsed > 70*time.Millisecond+50*time.Millisecond {
+		t.Errorf("Do ran for %v, past MaxElapsed", elapsed)
+	}
+	// 0ms, 20ms, 40ms, 60ms 尝试, 下一次等待会超过 70ms
+	if attempts < 2 || attempts > 4 {
+		t.Errorf("attempts = %d, want 2 to 4", attempts)
+	}
+	if exhausted.Attempts != attempts {
+		t.Errorf("Error.Attempts = %d, want %d", exhausted.Attempts, attempts)
+	}
+}
+
+func TestDoStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxAttempts: 10, InitialInterval: time.Hour}
+
+	attempts := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Do(ctx, func(context.Context) error {
+			attempts++
+			return errTemporary
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, errTemporary) {
+			t.Errorf("err = %v, want both context.Canceled and the last error", err)
+		}
+		if attempts != 1 {
+			t.Errorf("attempts = %d, want 1", attempts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do did not return after the context was canceled")
+	}
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// rowScanner 是 pgx.Rows 与 *sql.Rows 的公共部分
+type rowScanner interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+// scanOne 将第一行扫描到 dest, 没有数据时返回 false.
+// dest 可以是结构体指针, *map[string]interface{} 或单列的标量指针
+func scanOne(rows rowScanner, columns []string, dest interface{}) (bool, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return false, fmt.Errorf("result must be a non-nil pointer, got %T", dest)
+	}
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+	if err := scanRow(rows, columns, v.Elem()); err != nil {
+		return false, err
+	}
+	return true, rows.Err()
+}
+
+// scanAll 将所有行写入 dest 指向的切片, 支持 *[]T, *[]*T 和 *[]map[string]interface{}.
+// 与 MongoDB 的 cursor.All 一致, 切片先被截断为长度 0, 原有元素不保留
+func scanAll(rows rowScanner, columns []string, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("results must be a pointer to a slice, got %T", dest)
+	}
+
+	slice := v.Elem().Slice(0, 0)
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		if err := scanRow(rows, columns, elem.Elem()); err != nil {
+			return err
+		}
+		if isPtr {
+			slice = reflect.Append(slice, elem)
+		} else {
+			slice = reflect.Append(slice, elem.Elem())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	v.Elem().Set(slice)
+	return nil
+}
+
+// scanRow 将当前行扫描到可寻址的 v
+func scanRow(rows rowScanner, columns []string, v reflect.Value) error {
+	switch {
+	case v.Kind() == reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type %s", v.Type().Key())
+		}
+		values := make([]interface{}, len(columns))
+		targets := make([]interface{}, len(columns))
+		for i := range values {
+			targets[i] = &values[i]
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(columns)))
+		}
+		for i, col := range columns {
+			val := reflect.ValueOf(values[i])
+			if !val.IsValid() {
+				val = reflect.Zero(v.Type().Elem())
+			}
+			v.SetMapIndex(reflect.ValueOf(col).Convert(v.Type().Key()), val)
+		}
+		return nil
+
+	case isStructTarget(v.Type()):
+		info := getStructInfo(v.Type())
+		targets := make([]interface{}, len(columns))
+		var nullables []nullable
+		for i, col := range columns {
+			idx, ok := info.byColumn[strings.ToLower(col)]
+			if !ok {
+				// 结构体中没有对应字段的列直接丢弃
+				targets[i] = new(interface{})
+				continue
+			}
+			field := fieldByIndex(v, info.fields[idx].index)
+			if !acceptsNull(field.Type()) {
+				// 先扫描到 *T, NULL 时字段保持零值
+				n := nullable{field: field, ptr: reflect.New(reflect.PointerTo(field.Type()))}
+				nullables = append(nullables, n)
+				targets[i] = n.ptr.Interface()
+				continue
+			}
+			targets[i] = field.Addr().Interface()
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		for _, n := range nullables {
+			n.assign()
+		}
+		return nil
+
+	default:
+		if len(columns) != 1 {
+			return fmt.Errorf("cannot scan %d columns into %s", len(columns), v.Type())
+		}
+		return rows.Scan(v.Addr().Interface())
+	}
+}
+
+// nullable 将 **T 的扫描结果写回 T 类型的字段
+type nullable struct {
+	field reflect.Value
+	ptr   reflect.Value // **T
+}
+
+func (n nullable) assign() {
+	if p := n.ptr.Elem(); p.IsNil() {
+		n.field.Set(reflect.Zero(n.field.Type()))
+	} else {
+		n.field.Set(p.Elem())
+	}
+}
+
+// acceptsNull 判断类型能否直接接收 NULL: 指针, 接口和实现了 Scanner 的类型(如 sql.NullString)
+func acceptsNull(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return true
+	}
+	return reflect.PointerTo(t).Implements(scannerType)
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeRows 按行返回单列值
+type fakeRows struct {
+	values []interface{}
+	pos    int
+}
+
+func (r *fakeRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.values)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	reflect.ValueOf(dest[0]).Elem().Set(reflect.ValueOf(r.values[r.pos-1]))
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func TestScanAllResetsDestination(t *testing.T) {
+	results := []int64{100, 200, 300}
+	if err := scanAll(&fakeRows{values: []interface{}{int64(1)}}, []string{"id"}, &results); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, []int64{1}) {
+		t.Errorf("results = %v, want [1]", results)
+	}
+}
@@ -0,0 +1,286 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Cond 是可以组合的 WHERE 条件
+type Cond interface {
+	build(b *builder) error
+}
+
+type compare struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (c compare) build(b *builder) error {
+	col, err := b.ident(c.column)
+	if err != nil {
+		return err
+	}
+	b.write(col, " ", c.op, " ", b.arg(c.value))
+	return nil
+}
+
+// Eq 生成 column = value, value 为 nil 时生成 IS NULL
+func Eq(column string, value interface{}) Cond {
+	if value == nil {
+		return IsNull(column)
+	}
+	return compare{column, "=", value}
+}
+
+// Ne 生成 column <> value, value 为 nil 时生成 IS NOT NULL
+func Ne(column string, value interface{}) Cond {
+	if value == nil {
+		return IsNotNull(column)
+	}
+	return compare{column, "<>", value}
+}
+
+// Gt 生成 column > value
+func Gt(column string, value interface{}) Cond { return compare{column, ">", value} }
+
+// Gte 生成 column >= value
+func Gte(column string, value interface{}) Cond { return compare{column, ">=", value} }
+
+// Lt 生成 column < value
+func Lt(column string, value interface{}) Cond { return compare{column, "<", value} }
+
+// Lte 生成 column <= value
+func Lte(column string, value interface{}) Cond { return compare{column, "<=", value} }
+
+// Like 生成 column LIKE pattern
+func Like(column string, pattern string) Cond { return compare{column, "LIKE", pattern} }
+
+// NotLike 生成 column NOT LIKE pattern
+func NotLike(column string, pattern string) Cond { return compare{column, "NOT LIKE", pattern} }
+
+type in struct {
+	column string
+	values []interface{}
+	not    bool
+}
+
+func (c in) build(b *builder) error {
+	col, err := b.ident(c.column)
+	if err != nil {
+		return err
+	}
+	if len(c.values) == 0 {
+		// 空集合: IN 恒假, NOT IN 恒真
+		if c.not {
+			b.write("1=1")
+		} else {
+			b.write("1=0")
+		}
+		return nil
+	}
+
+	placeholders := make([]string, len(c.values))
+	for i, v := range c.values {
+		placeholders[i] = b.arg(v)
+	}
+	op := " IN ("
+	if c.not {
+		op = " NOT IN ("
+	}
+	b.write(col, op, strings.Join(placeholders, ", "), ")")
+	return nil
+}
+
+// In 生成 column IN (...), values 可以是多个参数或一个切片
+func In(column string, values ...interface{}) Cond {
+	return in{column: column, values: flatten(values)}
+}
+
+// NotIn 生成 column NOT IN (...)
+func NotIn(column string, values ...interface{}) Cond {
+	return in{column: column, values: flatten(values), not: true}
+}
+
+type between struct {
+	column    string
+	low, high interface{}
+}
+
+func (c between) build(b *builder) error {
+	col, err := b.ident(c.column)
+	if err != nil {
+		return err
+	}
+	b.write(col, " BETWEEN ", b.arg(c.low), " AND ", b.arg(c.high))
+	return nil
+}
+
+// Between 生成 column BETWEEN low AND high
+func Between(column string, low, high interface{}) Cond {
+	return between{column, low, high}
+}
+
+type null struct {
+	column string
+	not    bool
+}
+
+func (c null) build(b *builder) error {
+	col, err := b.ident(c.column)
+	if err != nil {
+		return err
+	}
+	if c.not {
+		b.write(col, " IS NOT NULL")
+	} else {
+		b.write(col, " IS NULL")
+	}
+	return nil
+}
+
+// IsNull 生成 column IS NULL
+func IsNull(column string) Cond { return null{column: column} }
+
+// IsNotNull 生成 column IS NOT NULL
+func IsNotNull(column string) Cond { return null{column: column, not: true} }
+
+type group struct {
+	op    string
+	conds []Cond
+}
+
+// present 返回非 nil 的子条件
+func (g group) present() []Cond {
+	conds := make([]Cond, 0, len(g.conds))
+	for _, c := range g.conds {
+		if c != nil {
+			conds = append(conds, c)
+		}
+	}
+	return conds
+}
+
+func (g group) build(b *builder) error {
+	conds := g.present()
+	switch len(conds) {
+	case 0:
+		// 空 AND 恒为真, 空 OR 恒为假
+		if g.op == "OR" {
+			b.write("1=0")
+		} else {
+			b.write("1=1")
+		}
+		return nil
+	case 1:
+		return conds[0].build(b)
+	}
+
+	b.write("(")
+	for i, c := range conds {
+		if i > 0 {
+			b.write(" ", g.op, " ")
+		}
+		if err := c.build(b); err != nil {
+			return err
+		}
+	}
+	b.write(")")
+	return nil
+}
+
+// And 组合条件, 全部成立时为真
+func And(conds ...Cond) Cond { return group{"AND", conds} }
+
+// Or 组合条件, 任一成立时为真. 没有子条件时不匹配任何行
+func Or(conds ...Cond) Cond { return group{"OR", conds} }
+
+type all struct{}
+
+func (all) build(b *builder) error {
+	b.write("1=1")
+	return nil
+}
+
+// All 匹配所有行, 用于明确要求 Update 或 Delete 影响整张表
+func All() Cond { return all{} }
+
+type not struct {
+	cond Cond
+}
+
+func (n not) build(b *builder) error {
+	if n.cond == nil {
+		return fmt.Errorf("NOT without a condition")
+	}
+	b.write("NOT (")
+	if err := n.cond.build(b); err != nil {
+		return err
+	}
+	b.write(")")
+	return nil
+}
+
+// Not 取反条件
+func Not(cond Cond) Cond { return not{cond} }
+
+// FromMap 将 map 转换为按键排序的等值条件. nil 生成 IS NULL, 切片生成 IN
+func FromMap(m map[string]interface{}) Cond {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conds := make([]Cond, len(keys))
+	for i, k := range keys {
+		conds[i] = fromValue(k, m[k])
+	}
+	return And(conds...)
+}
+
+// FromColumns 将等长的列名和值转换为等值条件, 保持给定顺序
+func FromColumns(columns []string, values []interface{}) (Cond, error) {
+	if len(columns) != len(values) {
+		return nil, fmt.Errorf("%d columns but %d values", len(columns), len(values))
+	}
+	conds := make([]Cond, len(columns))
+	for i, col := range columns {
+		conds[i] = fromValue(col, values[i])
+	}
+	return And(conds...), nil
+}
+
+func fromValue(column string, value interface{}) Cond {
+	if isList(value) {
+		return In(column, value)
+	}
+	return Eq(column, value)
+}
+
+// isList 判断值是否应展开为 IN 列表, []byte 作为单个值
+func isList(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.([]byte); ok {
+		return false
+	}
+	k := reflect.TypeOf(v).Kind()
+	return k == reflect.Slice || k == reflect.Array
+}
+
+// flatten 展开作为唯一参数传入的切片
+func flatten(values []interface{}) []interface{} {
+	if len(values) != 1 || !isList(values[0]) {
+		return values
+	}
+	rv := reflect.ValueOf(values[0])
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
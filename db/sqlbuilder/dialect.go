@@ -0,0 +1,81 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialect 描述数据库在标识符引用, 占位符和分页上的差异
+type Dialect interface {
+	// Name 返回方言名称
+	Name() string
+	// QuoteIdent 引用单个标识符
+	QuoteIdent(name string) string
+	// Placeholder 返回第 n 个参数的占位符, n 从 1 开始
+	Placeholder(n int) string
+	// MaxIdentLength 返回标识符的最大长度
+	MaxIdentLength() int
+}
+
+var (
+	// Postgres 使用 "name" 引用和 $1 占位符
+	Postgres Dialect = postgres{}
+	// SQLServer 使用 [name] 引用和 @p1 占位符
+	SQLServer Dialect = sqlServer{}
+)
+
+type postgres struct{}
+
+func (postgres) Name() string { return "postgres" }
+
+func (postgres) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (postgres) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (postgres) MaxIdentLength() int { return 63 }
+
+type sqlServer struct{}
+
+func (sqlServer) Name() string { return "sqlserver" }
+
+func (sqlServer) QuoteIdent(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+func (sqlServer) Placeholder(n int) string { return "@p" + strconv.Itoa(n) }
+
+func (sqlServer) MaxIdentLength() int { return 128 }
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// ValidateIdent 检查标识符是否为 schema.name 形式的合法名称
+func ValidateIdent(d Dialect, ident string) error {
+	if ident == "" {
+		return fmt.Errorf("empty identifier")
+	}
+	for _, part := range strings.Split(ident, ".") {
+		if !identPattern.MatchString(part) {
+			return fmt.Errorf("invalid identifier %q", ident)
+		}
+		if len(part) > d.MaxIdentLength() {
+			return fmt.Errorf("identifier %q exceeds %d characters", part, d.MaxIdentLength())
+		}
+	}
+	return nil
+}
+
+// QuoteIdent 校验并引用 schema.name 形式的标识符
+func QuoteIdent(d Dialect, ident string) (string, error) {
+	if err := ValidateIdent(d, ident); err != nil {
+		return "", err
+	}
+	parts := strings.Split(ident, ".")
+	for i, part := range parts {
+		parts[i] = d.QuoteIdent(part)
+	}
+	return strings.Join(parts, "."), nil
+}
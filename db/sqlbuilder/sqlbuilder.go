@@ -0,0 +1,345 @@
+// Package sqlbuilder 生成参数化的 SQL 语句, 供 PostgreSQL 和 SQL Server 驱动共用.
+// 标识符经过校验并按方言引用, 所有值都作为参数传递
+package sqlbuilder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNoCondition 表示 UPDATE 或 DELETE 没有条件, 会影响所有行. 需要影响所有行时传入 All()
+var ErrNoCondition = errors.New("update or delete without a condition")
+
+type builder struct {
+	dialect Dialect
+	sb      strings.Builder
+	args    []interface{}
+}
+
+func (b *builder) write(parts ...string) {
+	for _, p := range parts {
+		b.sb.WriteString(p)
+	}
+}
+
+func (b *builder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return b.dialect.Placeholder(len(b.args))
+}
+
+func (b *builder) ident(name string) (string, error) {
+	return QuoteIdent(b.dialect, name)
+}
+
+func (b *builder) idents(names []string) ([]string, error) {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		q, err := b.ident(name)
+		if err != nil {
+			return nil, err
+		}
+		quoted[i] = q
+	}
+	return quoted, nil
+}
+
+func (b *builder) where(cond Cond) error {
+	if isEmpty(cond) {
+		return nil
+	}
+	if _, ok := cond.(all); ok {
+		return nil
+	}
+	b.write(" WHERE ")
+	return cond.build(b)
+}
+
+// mutationWhere 与 where 相同, 但条件为空时返回 ErrNoCondition, 避免误改整张表
+func (b *builder) mutationWhere(table string, cond Cond) error {
+	if isEmpty(cond) {
+		return fmt.Errorf("%s: %w", table, ErrNoCondition)
+	}
+	return b.where(cond)
+}
+
+func (b *builder) result() (string, []interface{}, error) {
+	return b.sb.String(), b.args, nil
+}
+
+// isEmpty 判断条件是否不含任何约束: nil, 只包含空条件的 AND, 或含有空条件的 OR.
+// 没有子条件的 OR 不匹配任何行, All 是明确的条件, 都不算空
+func isEmpty(cond Cond) bool {
+	switch c := cond.(type) {
+	case nil:
+		return true
+	case group:
+		conds := c.present()
+		if c.op == "OR" {
+			for _, sub := range conds {
+				if isEmpty(sub) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, sub := range conds {
+			if !isEmpty(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Where 单独生成条件表达式 (不含 WHERE 关键字), 参数编号从 1 开始
+func Where(d Dialect, cond Cond) (string, []interface{}, error) {
+	b := &builder{dialect: d}
+	if cond == nil {
+		return "", nil, nil
+	}
+	if err := cond.build(b); err != nil {
+		return "", nil, err
+	}
+	return b.result()
+}
+
+// Order 描述一个排序字段
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// ParseOrder 解析 "name", "+name" 或 "-name" 形式的排序字段, "-" 表示降序
+func ParseOrder(s string) Order {
+	switch {
+	case strings.HasPrefix(s, "-"):
+		return Order{Column: s[1:], Desc: true}
+	case strings.HasPrefix(s, "+"):
+		return Order{Column: s[1:]}
+	}
+	return Order{Column: s}
+}
+
+// SelectBuilder 构造 SELECT 语句
+type SelectBuilder struct {
+	dialect Dialect
+	table   string
+	columns []string
+	where   Cond
+	orders  []Order
+	limit   int
+	offset  int
+}
+
+// Select 开始一个 SELECT 语句, 未指定列时选择 *
+func Select(d Dialect, table string, columns ...string) *SelectBuilder {
+	return &SelectBuilder{dialect: d, table: table, columns: columns}
+}
+
+// Where 设置查询条件
+func (s *SelectBuilder) Where(cond Cond) *SelectBuilder {
+	s.where = cond
+	return s
+}
+
+// OrderBy 追加排序字段, 格式见 ParseOrder
+func (s *SelectBuilder) OrderBy(fields ...string) *SelectBuilder {
+	for _, f := range fields {
+		s.orders = append(s.orders, ParseOrder(f))
+	}
+	return s
+}
+
+// Order 追加排序字段
+func (s *SelectBuilder) Order(orders ...Order) *SelectBuilder {
+	s.orders = append(s.orders, orders...)
+	return s
+}
+
+// Limit 设置最多返回的行数, 0 表示不限制
+func (s *SelectBuilder) Limit(n int) *SelectBuilder {
+	s.limit = n
+	return s
+}
+
+// Offset 设置跳过的行数
+func (s *SelectBuilder) Offset(n int) *SelectBuilder {
+	s.offset = n
+	return s
+}
+
+// Build 生成 SQL 和参数
+func (s *SelectBuilder) Build() (string, []interface{}, error) {
+	if s.limit < 0 || s.offset < 0 {
+		return "", nil, fmt.Errorf("negative limit or offset")
+	}
+
+	b := &builder{dialect: s.dialect}
+	table, err := b.ident(s.table)
+	if err != nil {
+		return "", nil, err
+	}
+	cols := []string{"*"}
+	if len(s.columns) > 0 {
+		if cols, err = b.idents(s.columns); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// SQL Server 没有 LIMIT, 无偏移时使用 TOP, 有偏移时使用 OFFSET ... FETCH
+	_, mssql := s.dialect.(sqlServer)
+	b.write("SELECT ")
+	if mssql && s.limit > 0 && s.offset == 0 {
+		b.write("TOP (", strconv.Itoa(s.limit), ") ")
+	}
+	b.write(strings.Join(cols, ", "), " FROM ", table)
+
+	if err := b.where(s.where); err != nil {
+		return "", nil, err
+	}
+
+	if len(s.orders) > 0 {
+		parts := make([]string, len(s.orders))
+		for i, o := range s.orders {
+			col, err := b.ident(o.Column)
+			if err != nil {
+				return "", nil, err
+			}
+			if o.Desc {
+				col += " DESC"
+			} else {
+				col += " ASC"
+			}
+			parts[i] = col
+		}
+		b.write(" ORDER BY ", strings.Join(parts, ", "))
+	}
+
+	if mssql {
+		if s.offset > 0 {
+			if len(s.orders) == 0 {
+				// OFFSET 要求 ORDER BY
+				b.write(" ORDER BY (SELECT NULL)")
+			}
+			b.write(" OFFSET ", strconv.Itoa(s.offset), " ROWS")
+			if s.limit > 0 {
+				b.write(" FETCH NEXT ", strconv.Itoa(s.limit), " ROWS ONLY")
+			}
+		}
+	} else {
+		if s.limit > 0 {
+			b.write(" LIMIT ", strconv.Itoa(s.limit))
+		}
+		if s.offset > 0 {
+			b.write(" OFFSET ", strconv.Itoa(s.offset))
+		}
+	}
+
+	return b.result()
+}
+
+// Insert 生成 INSERT 语句, columns 和 values 一一对应
+func Insert(d Dialect, table string, columns []string, values []interface{}) (string, []interface{}, error) {
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("no columns to insert into %s", table)
+	}
+	if len(columns) != len(values) {
+		return "", nil, fmt.Errorf("%d columns but %d values", len(columns), len(values))
+	}
+
+	b := &builder{dialect: d}
+	t, err := b.ident(table)
+	if err != nil {
+		return "", nil, err
+	}
+	cols, err := b.idents(columns)
+	if err != nil {
+		return "", nil, err
+	}
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = b.arg(v)
+	}
+
+	b.write("INSERT INTO ", t, " (", strings.Join(cols, ", "), ") VALUES (", strings.Join(placeholders, ", "), ")")
+	return b.result()
+}
+
+// Update 生成 UPDATE 语句, SET 参数在 WHERE 参数之前.
+// cond 为空时返回 ErrNoCondition, 更新所有行需要传入 All()
+func Update(d Dialect, table string, columns []string, values []interface{}, cond Cond) (string, []interface{}, error) {
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("no columns to update in %s", table)
+	}
+	if len(columns) != len(values) {
+		return "", nil, fmt.Errorf("%d columns but %d values", len(columns), len(values))
+	}
+
+	b := &builder{dialect: d}
+	t, err := b.ident(table)
+	if err != nil {
+		return "", nil, err
+	}
+	cols, err := b.idents(columns)
+	if err != nil {
+		return "", nil, err
+	}
+	sets := make([]string, len(cols))
+	for i, col := range cols {
+		sets[i] = col + " = " + b.arg(values[i])
+	}
+
+	b.write("UPDATE ", t, " SET ", strings.Join(sets, ", "))
+	if err := b.mutationWhere(table, cond); err != nil {
+		return "", nil, err
+	}
+	return b.result()
+}
+
+// Delete 生成 DELETE 语句. cond 为空时返回 ErrNoCondition, 删除所有行需要传入 All()
+func Delete(d Dialect, table string, cond Cond) (string, []interface{}, error) {
+	b := &builder{dialect: d}
+	t, err := b.ident(table)
+	if err != nil {
+		return "", nil, err
+	}
+
+	b.write("DELETE FROM ", t)
+	if err := b.mutationWhere(table, cond); err != nil {
+		return "", nil, err
+	}
+	return b.result()
+}
+
+// Query 组合列, 条件, 排序和分页, 可以作为 SQL 驱动 Read/List 的 filter
+type Query struct {
+	// Columns 为空时选择所有列
+	Columns []string
+	Where   Cond
+	Order   []Order
+	Limit   int
+	Offset  int
+}
+
+// Select 按查询生成 SELECT 语句
+func (q *Query) Select(d Dialect, table string) *SelectBuilder {
+	return Select(d, table, q.Columns...).Where(q.Where).Order(q.Order...).Limit(q.Limit).Offset(q.Offset)
+}
+
+// Count 生成 SELECT COUNT(*) 语句
+func Count(d Dialect, table string, cond Cond) (string, []interface{}, error) {
+	b := &builder{dialect: d}
+	t, err := b.ident(table)
+	if err != nil {
+		return "", nil, err
+	}
+
+	b.write("SELECT COUNT(*) FROM ", t)
+	if err := b.where(cond); err != nil {
+		return "", nil, err
+	}
+	return b.result()
+}
@@ -0,0 +1,51 @@
+package sqlbuilder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMutationsRequireCondition(t *testing.T) {
+	empty := map[string]Cond{
+		"nil":                  nil,
+		"empty and":            And(),
+		"nested":               And(nil, And()),
+		"from map":             FromMap(nil),
+		"or with empty branch": Or(Eq("id", 1), And()),
+	}
+	for name, cond := range empty {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := Delete(Postgres, "users", cond); !errors.Is(err, ErrNoCondition) {
+				t.Errorf("Delete: got %v, want ErrNoCondition", err)
+			}
+			if _, _, err := Update(Postgres, "users", []string{"age"}, []interface{}{1}, cond); !errors.Is(err, ErrNoCondition) {
+				t.Errorf("Update: got %v, want ErrNoCondition", err)
+			}
+		})
+	}
+}
+
+func TestMutationsWithAll(t *testing.T) {
+	query, args, err := Delete(Postgres, "users", All())
+	if err != nil || query != `DELETE FROM "users"` || len(args) != 0 {
+		t.Errorf("Delete(All()) = %q, %v, %v", query, args, err)
+	}
+	query, args, err = Update(SQLServer, "users", []string{"age"}, []interface{}{1}, All())
+	if err != nil || query != `UPDATE [users] SET [age] = @p1` || len(args) != 1 {
+		t.Errorf("Update(All()) = %q, %v, %v", query, args, err)
+	}
+	query, _, err = Delete(Postgres, "users", Eq("id", 0))
+	if err != nil || query != `DELETE FROM "users" WHERE "id" = $1` {
+		t.Errorf("Delete(Eq) = %q, %v", query, err)
+	}
+}
+
+func TestEmptyOrMatchesNothing(t *testing.T) {
+	query, _, err := Delete(Postgres, "users", Or())
+	if err != nil || query != `DELETE FROM "users" WHERE 1=0` {
+		t.Errorf("Delete(Or()) = %q, %v", query, err)
+	}
+	if _, _, err := Delete(Postgres, "users", Not(nil)); err == nil {
+		t.Error("Delete(Not(nil)) should fail")
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/Dankko0w0/gospike/db/q"
+	"github.com/Dankko0w0/gospike/db/sqlbuilder"
+)
+
+// sqlQuery 将 SQL 驱动的 filter 参数转换为查询.
+// filter 可以是 q.Query, sqlbuilder.Query, sqlbuilder.Cond, map 或带 db 标签的结构体,
+// map 和结构体生成等值条件, nil 值生成 IS NULL, 切片值生成 IN
+func sqlQuery(filter interface{}) (*sqlbuilder.Query, error) {
+	switch f := filter.(type) {
+	case q.Query:
+		return sqlFromQuery(f)
+	case *q.Query:
+		if f == nil {
+			return &sqlbuilder.Query{}, nil
+		}
+		return sqlFromQuery(*f)
+	case *sqlbuilder.Query:
+		if f == nil {
+			return &sqlbuilder.Query{}, nil
+		}
+		return f, nil
+	case sqlbuilder.Query:
+		return &f, nil
+	case sqlbuilder.Cond:
+		return &sqlbuilder.Query{Where: f}, nil
+	}
+
+	columns, values, err := columnValues(filter, modeFilter)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := sqlbuilder.FromColumns(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlbuilder.Query{Where: cond}, nil
+}
+
+// sqlWhere 返回 filter 的条件部分, 用于 Update 和 Delete.
+// 条件为空 (nil, 空 map 或全为零值的结构体) 时 sqlbuilder 返回 ErrNoCondition,
+// 影响所有行需要传入 q.All() 或 sqlbuilder.All()
+func sqlWhere(filter interface{}) (sqlbuilder.Cond, error) {
+	query, err := sqlQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	return query.Where, nil
+}
+
+// keysetQuery 在 filter 的条件上追加 key > after, 按 key 升序并限制条数
+func keysetQuery(filter interface{}, key string, after interface{}, limit int) (*sqlbuilder.Query, error) {
+	query, err := sqlQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	keyset := *query
+	if after != nil {
+		keyset.Where = sqlbuilder.And(query.Where, sqlbuilder.Gt(key, after))
+	}
+	keyset.Order = []sqlbuilder.Order{{Column: key}}
+	keyset.Limit = limit
+	keyset.Offset = 0
+	return &keyset, nil
+}
+
+// sqlFromQuery 将 q.Query 翻译为 sqlbuilder.Query
+func sqlFromQuery(query q.Query) (*sqlbuilder.Query, error) {
+	where, err := sqlCond(query.Filter())
+	if err != nil {
+		return nil, err
+	}
+	result := &sqlbuilder.Query{
+		Columns: query.Fields(),
+		Where:   where,
+		Limit:   query.LimitValue(),
+		Offset:  query.OffsetValue(),
+	}
+	for _, s := range query.SortFields() {
+		result.Order = append(result.Order, sqlbuilder.Order{Column: s.Field, Desc: s.Desc})
+	}
+	return result, nil
+}
+
+// sqlCond 将 q 表达式翻译为 sqlbuilder 条件
+func sqlCond(e q.Expr) (sqlbuilder.Cond, error) {
+	switch e := e.(type) {
+	case nil:
+		return nil, nil
+	case q.Cond:
+		switch e.Op {
+		case q.OpEq:
+			return sqlbuilder.Eq(e.Field, e.Value), nil
+		case q.OpNe:
+			return sqlbuilder.Ne(e.Field, e.Value), nil
+		case q.OpGt:
+			return sqlbuilder.Gt(e.Field, e.Value), nil
+		case q.OpGte:
+			return sqlbuilder.Gte(e.Field, e.Value), nil
+		case q.OpLt:
+			return sqlbuilder.Lt(e.Field, e.Value), nil
+		case q.OpLte:
+			return sqlbuilder.Lte(e.Field, e.Value), nil
+		case q.OpIn:
+			return sqlbuilder.In(e.Field, e.Value), nil
+		case q.OpNotIn:
+			return sqlbuilder.NotIn(e.Field, e.Value), nil
+		case q.OpLike:
+			pattern, ok := e.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("like pattern for %s must be a string, got %T", e.Field, e.Value)
+			}
+			return sqlbuilder.Like(e.Field, pattern), nil
+		case q.OpIsNull:
+			return sqlbuilder.IsNull(e.Field), nil
+		case q.OpNotNull:
+			return sqlbuilder.IsNotNull(e.Field), nil
+		}
+		return nil, fmt.Errorf("unsupported operator %q", e.Op)
+	case q.And:
+		conds, err := sqlConds(e)
+		if err != nil {
+			return nil, err
+		}
+		return sqlbuilder.And(conds...), nil
+	case q.Or:
+		conds, err := sqlConds(e)
+		if err != nil {
+			return nil, err
+		}
+		return sqlbuilder.Or(conds...), nil
+	case q.MatchAll:
+		return sqlbuilder.All(), nil
+	case q.Not:
+		if e.Expr == nil {
+			return nil, fmt.Errorf("not expression without an inner expression")
+		}
+		cond, err := sqlCond(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return sqlbuilder.Not(cond), nil
+	}
+	return nil, fmt.Errorf("unsupported expression %T", e)
+}
+
+func sqlConds(exprs []q.Expr) ([]sqlbuilder.Cond, error) {
+	conds := make([]sqlbuilder.Cond, len(exprs))
+	for i, e := range exprs {
+		c, err := sqlCond(e)
+		if err != nil {
+			return nil, err
+		}
+		conds[i] = c
+	}
+	return conds, nil
+}
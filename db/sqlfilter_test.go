@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/db/q"
+	"github.com/Dankko0w0/gospike/db/sqlbuilder"
+)
+
+type filterUser struct {
+	ID   int64  `db:"id,pk"`
+	Name string `db:"name"`
+}
+
+func TestSQLMutationsRejectEmptyFilters(t *testing.T) {
+	ctx := context.Background()
+	pg := NewPostgreSQL(&Config{})
+	ss := NewSQLServer(&Config{})
+
+	for _, filter := range []interface{}{nil, filterUser{}, map[string]interface{}{}} {
+		for name, op := range map[string]DataOperation{"postgresql": pg, "sqlserver": ss} {
+			if err := op.Delete(ctx, "users", filter); !errors.Is(err, sqlbuilder.ErrNoCondition) {
+				t.Errorf("%s Delete(%#v): got %v, want ErrNoCondition", name, filter, err)
+			}
+			if err := op.Update(ctx, "users", filter, map[string]interface{}{"name": "x"}); !errors.Is(err, sqlbuilder.ErrNoCondition) {
+				t.Errorf("%s Update(%#v): got %v, want ErrNoCondition", name, filter, err)
+			}
+		}
+	}
+
+	// 明确使用 All 时条件检查通过, 因为未连接而失败
+	if err := pg.Delete(ctx, "users", sqlbuilder.All()); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Delete(All()): got %v, want ErrNotConnected", err)
+	}
+}
+
+func TestSQLCondTranslation(t *testing.T) {
+	tests := []struct {
+		name  string
+		query q.Query
+		want  string
+	}{
+		{"empty or matches nothing", q.Where(q.Or{}), `SELECT * FROM "users" WHERE 1=0`},
+		{"any without alternatives", q.Any(), `SELECT * FROM "users" WHERE 1=0`},
+		{"any with empty alternative", q.Any(q.Eq("id", 1), q.New()), `SELECT * FROM "users"`},
+		{"not", q.New().Not(q.Eq("id", 1)), `SELECT * FROM "users" WHERE NOT ("id" = $1)`},
+		{"all", q.All(), `SELECT * FROM "users" WHERE 1=1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := sqlQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _, err := query.Select(sqlbuilder.Postgres, "users").Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLCondRejectsNotWithoutExpr(t *testing.T) {
+	if _, err := sqlQuery(q.Where(q.Not{})); err == nil {
+		t.Fatal("expected an error for q.Not without an inner expression")
+	}
+	if _, _, err := sqlbuilder.Delete(sqlbuilder.Postgres, "users", sqlbuilder.Not(nil)); err == nil {
+		t.Fatal("expected an error for sqlbuilder.Not(nil)")
+	}
+}
+
+func TestSQLMutationsWithQAll(t *testing.T) {
+	ctx := context.Background()
+	pg := NewPostgreSQL(&Config{})
+	if err := pg.Delete(ctx, "users", q.New()); !errors.Is(err, ErrNoCondition) {
+		t.Errorf("Delete(q.New()): got %v, want ErrNoCondition", err)
+	}
+	if err := pg.Delete(ctx, "users", q.All()); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Delete(q.All()): got %v, want ErrNotConnected", err)
+	}
+}
@@ -3,168 +3,233 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/Dankko0w0/gospike/db/retry"
+	"github.com/Dankko0w0/gospike/db/sqlbuilder"
 	mssql "github.com/microsoft/go-mssqldb"
 )
 
 type SQLServer struct {
-	config     *Config
-	db         *sql.DB
-	connected  bool
-	maxRetries int
+	config *Config
+	db     clientHolder[*sql.DB]
+	retrier
 }
 
 func NewSQLServer(config *Config) *SQLServer {
 	return &SQLServer{
-		config:     config,
-		maxRetries: 3,
+		config:  config,
+		retrier: newRetrier(sqlServerConnRetryable),
 	}
 }
 
 func (s *SQLServer) Connect(ctx context.Context) error {
+	opts, err := s.config.ParseOptions()
+	if err != nil {
+		return err
+	}
+	params, err := sqlServerParams(opts)
+	if err != nil {
+		return err
+	}
+
 	// 构建连接字符串
-	connString := fmt.Sprintf("sqlserver://%s:%s@%s:%d?database=%s",
-		s.config.Username,
-		s.config.Password,
-		s.config.Host,
-		s.config.Port,
-		s.config.Database,
-	)
+	connString, err := withParams(s.connString(), params)
+	if err != nil {
+		return err
+	}
 
 	// 创建连接器
 	connector, err := mssql.NewConnector(connString)
 	if err != nil {
-		return fmt.Errorf("failed to create SQL Server connector: %v", err)
+		return retry.Permanent(fmt.Errorf("failed to create SQL Server connector: %w", err))
 	}
 
 	// 创建数据库连接
 	db := sql.OpenDB(connector)
 
-	// 设置连接池参数
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(time.Minute * 5)
+	// 设置连接池参数, 未配置时使用 25/5/5m
+	maxConns, maxIdle, lifetime := 25, 5, 5*time.Minute
+	if opts.MaxConns > 0 {
+		maxConns = opts.MaxConns
+	}
+	if opts.MaxIdleConns > 0 {
+		maxIdle = opts.MaxIdleConns
+	}
+	if opts.ConnMaxLifetime > 0 {
+		lifetime = opts.ConnMaxLifetime
+	}
+	db.SetMaxOpenConns(maxConns)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(lifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
 
 	// 测试连接
 	err = db.PingContext(ctx)
 	if err != nil {
 		db.Close()
-		return fmt.Errorf("failed to ping SQL Server: %v", err)
+		return fmt.Errorf("failed to ping SQL Server: %w", err)
 	}
 
-	s.db = db
-	s.connected = true
+	// 已连接时替换旧连接池, 旧连接池在进行中的调用结束后关闭
+	s.db.replace(ctx, "sqlserver", db, func(context.Context) error {
+		if err := db.Close(); err != nil {
+			return fmt.Errorf("failed to disconnect from SQL Server: %v", err)
+		}
+		return nil
+	})
 	return nil
 }
 
+// Disconnect 等待进行中的调用结束后关闭连接池, 可以重复调用
 func (s *SQLServer) Disconnect(ctx context.Context) error {
-	if s.db != nil {
-		err := s.db.Close()
-		if err != nil {
-			return fmt.Errorf("failed to disconnect from SQL Server: %v", err)
-		}
-		s.connected = false
-	}
-	return nil
+	return s.db.disconnect(ctx)
 }
 
 func (s *SQLServer) Ping(ctx context.Context) error {
-	if s.db == nil {
-		return fmt.Errorf("database not connected")
+	db, release, err := s.db.acquire()
+	if err != nil {
+		return err
 	}
-	return s.db.PingContext(ctx)
+	defer release()
+	return db.PingContext(ctx)
 }
 
 func (s *SQLServer) IsConnected() bool {
-	return s.connected
+	return s.db.connected()
 }
 
+// Reconnect 按 RetryPolicy 建立新连接池并替换旧连接池, 见 SetRetryPolicy
 func (s *SQLServer) Reconnect(ctx context.Context) error {
-	s.Disconnect(ctx)
-
-	for i := 0; i < s.maxRetries; i++ {
-		err := s.Connect(ctx)
-		if err == nil {
-			return nil
-		}
-		time.Sleep(time.Second * time.Duration(i+1))
-	}
-	return fmt.Errorf("failed to reconnect after %d attempts", s.maxRetries)
+	return reconnect(ctx, "sqlserver", s.RetryPolicy(), s.Connect)
 }
 
 // CRUD operations
-func (s *SQLServer) Create(ctx context.Context, table string, data map[string]interface{}) error {
-	columns := make([]string, 0, len(data))
-	values := make([]interface{}, 0, len(data))
-	placeholders := make([]string, 0, len(data))
-
-	i := 0
-	for col, val := range data {
-		columns = append(columns, col)
-		values = append(values, val)
-		placeholders = append(placeholders, fmt.Sprintf("@p%d", i))
-		i++
-	}
-
-	query := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s)",
-		table,
-		joinStrings(columns, ","),
-		joinStrings(placeholders, ","),
-	)
-
-	_, err := s.db.ExecContext(ctx, query, values...)
+// data 和 update 可以是 map[string]interface{} 或带 db 标签的结构体, map 的列按名称排序.
+// filter 还可以是 q.Query, sqlbuilder.Cond 或 sqlbuilder.Query, 结构体作为 filter 时只使用非零值字段.
+// 标识符使用 [name] 引用
+func (s *SQLServer) Create(ctx context.Context, table string, data interface{}) error {
+	columns, values, err := columnValues(data, modeWrite)
+	if err != nil {
+		return err
+	}
+	query, args, err := sqlbuilder.Insert(sqlbuilder.SQLServer, table, columns, values)
+	if err != nil {
+		return err
+	}
+
+	db, release, err := s.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = db.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (s *SQLServer) Read(ctx context.Context, table string, filter map[string]interface{}, result interface{}) error {
-	whereClause, values := buildWhereClause(filter)
-	query := fmt.Sprintf("SELECT * FROM %s WHERE %s", table, whereClause)
+func (s *SQLServer) Read(ctx context.Context, table string, filter interface{}, result interface{}) error {
+	q, err := sqlQuery(filter)
+	if err != nil {
+		return err
+	}
+	query, args, err := q.Select(sqlbuilder.SQLServer, table).Limit(1).Build()
+	if err != nil {
+		return err
+	}
 
-	row := s.db.QueryRowContext(ctx, query, values...)
-	return row.Scan(result)
-}
+	db, release, err := s.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-func (s *SQLServer) Update(ctx context.Context, table string, filter map[string]interface{}, update map[string]interface{}) error {
-	setClauses := make([]string, 0, len(update))
-	values := make([]interface{}, 0, len(update)+len(filter))
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-	i := 0
-	for col, val := range update {
-		setClauses = append(setClauses, fmt.Sprintf("%s = @p%d", col, i))
-		values = append(values, val)
-		i++
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	found, err := scanOne(rows, columns, result)
+	if err != nil {
+		return err
 	}
+	if !found {
+		return &NotFoundError{Collection: table}
+	}
+	return nil
+}
 
-	whereClause, whereValues := buildWhereClause(filter)
-	values = append(values, whereValues...)
+func (s *SQLServer) Update(ctx context.Context, table string, filter interface{}, update interface{}) error {
+	columns, values, err := columnValues(update, modeWrite)
+	if err != nil {
+		return err
+	}
+	where, err := sqlWhere(filter)
+	if err != nil {
+		return err
+	}
+	query, args, err := sqlbuilder.Update(sqlbuilder.SQLServer, table, columns, values, where)
+	if err != nil {
+		return err
+	}
 
-	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE %s",
-		table,
-		joinStrings(setClauses, ","),
-		whereClause,
-	)
+	db, release, err := s.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	_, err := s.db.ExecContext(ctx, query, values...)
+	_, err = db.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (s *SQLServer) Delete(ctx context.Context, table string, filter map[string]interface{}) error {
-	whereClause, values := buildWhereClause(filter)
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s", table, whereClause)
+func (s *SQLServer) Delete(ctx context.Context, table string, filter interface{}) error {
+	where, err := sqlWhere(filter)
+	if err != nil {
+		return err
+	}
+	query, args, err := sqlbuilder.Delete(sqlbuilder.SQLServer, table, where)
+	if err != nil {
+		return err
+	}
+
+	db, release, err := s.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	_, err := s.db.ExecContext(ctx, query, values...)
+	_, err = db.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (s *SQLServer) List(ctx context.Context, table string, filter map[string]interface{}, results interface{}) error {
-	whereClause, values := buildWhereClause(filter)
-	query := fmt.Sprintf("SELECT * FROM %s WHERE %s", table, whereClause)
+func (s *SQLServer) List(ctx context.Context, table string, filter interface{}, results interface{}) error {
+	q, err := sqlQuery(filter)
+	if err != nil {
+		return err
+	}
+	query, args, err := q.Select(sqlbuilder.SQLServer, table).Build()
+	if err != nil {
+		return err
+	}
+
+	db, release, err := s.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	rows, err := s.db.QueryContext(ctx, query, values...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -173,39 +238,213 @@ func (s *SQLServer) List(ctx context.Context, table string, filter map[string]in
 	return scanRows(rows, results)
 }
 
-// 辅助函数
-func joinStrings(strs []string, sep string) string {
-	result := ""
-	for i, s := range strs {
-		if i > 0 {
-			result += sep
+// Count 返回满足 filter 的记录数, 见 Counter
+func (s *SQLServer) Count(ctx context.Context, table string, filter interface{}) (int64, error) {
+	where, err := sqlWhere(filter)
+	if err != nil {
+		return 0, err
+	}
+	query, args, err := sqlbuilder.Count(sqlbuilder.SQLServer, table, where)
+	if err != nil {
+		return 0, err
+	}
+
+	db, release, err := s.querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var n int64
+	err = db.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// ListAfter 按 key 做键集分页, 见 KeysetLister
+func (s *SQLServer) ListAfter(ctx context.Context, table string, filter interface{}, key string, after interface{}, limit int, results interface{}) error {
+	q, err := keysetQuery(filter, key, after, limit)
+	if err != nil {
+		return err
+	}
+	query, args, err := q.Select(sqlbuilder.SQLServer, table).Build()
+	if err != nil {
+		return err
+	}
+
+	db, release, err := s.querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return scanRows(rows, results)
+}
+
+// WithTx 在事务中执行 fn, 见 Transactor. 死锁 (1205) 和快照更新冲突 (3960) 时重试整个事务.
+// 嵌套事务使用 SAVE TRANSACTION, 内层成功时不做操作, 失败时回滚到保存点.
+// go-mssqldb 不支持只读事务, 使用 WithReadOnly 时返回 errors.ErrUnsupported
+func (s *SQLServer) WithTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	o := newTxOptions(opts)
+	if o.ReadOnly {
+		return fmt.Errorf("sqlserver: read-only transactions: %w", errors.ErrUnsupported)
+	}
+
+	if outer, ok := txFromContext(ctx, s).(*sqlServerTx); ok {
+		inner := &sqlServerTx{Tx: outer.Tx, depth: outer.depth + 1}
+		savepoint := fmt.Sprintf("sp_%d", inner.depth)
+		if _, err := outer.ExecContext(ctx, "SAVE TRANSACTION "+savepoint); err != nil {
+			return err
+		}
+		return runTx(contextWithTx(ctx, s, inner), fn,
+			func() error { return nil },
+			func() error {
+				_, err := outer.ExecContext(ctx, "ROLLBACK TRANSACTION "+savepoint)
+				return err
+			})
+	}
+
+	// 事务期间持有连接池, 重连不会关闭它
+	db, release, err := s.db.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	txOptions := &sql.TxOptions{Isolation: sqlIsoLevel(o.Isolation)}
+	return retryTx(ctx, "sqlserver", o.MaxRetries, sqlServerRetryable, func() error {
+		tx, err := db.BeginTx(ctx, txOptions)
+		if err != nil {
+			return err
 		}
-		result += s
+		return runTx(contextWithTx(ctx, s, &sqlServerTx{Tx: tx}), fn, tx.Commit, tx.Rollback)
+	})
+}
+
+// 辅助函数
+// connString 返回 DSN, 未配置时根据各字段构造 URI
+func (s *SQLServer) connString() string {
+	if s.config.DSN != "" {
+		return s.config.DSN
 	}
-	return result
+	u := s.config.buildURL("sqlserver")
+	if s.config.Database != "" {
+		u.RawQuery = url.Values{"database": {s.config.Database}}.Encode()
+	}
+	return u.String()
 }
 
-func buildWhereClause(filter map[string]interface{}) (string, []interface{}) {
-	if len(filter) == 0 {
-		return "1=1", nil
+// sqlServerParams 将超时, TLS 和应用名称选项转换为 go-mssqldb 连接参数
+func sqlServerParams(opts *Options) (url.Values, error) {
+	if opts.TLS.CertFile != "" {
+		return nil, fmt.Errorf("SQL Server does not support tls client certificates")
+	}
+
+	params := url.Values{}
+	switch opts.SSLMode {
+	case "disable":
+		params.Set("encrypt", "disable")
+	case "allow", "prefer":
+		params.Set("encrypt", "false")
+	case "require":
+		params.Set("encrypt", "true")
+		if opts.TLS.CAFile == "" {
+			// 配置了 CA 时校验服务器证书
+			params.Set("trustservercertificate", "true")
+		}
+	case "verify-ca", "verify-full":
+		params.Set("encrypt", "true")
+	default:
+		if opts.tlsEnabled() {
+			params.Set("encrypt", "true")
+		}
+	}
+	if opts.TLS.InsecureSkipVerify {
+		params.Set("trustservercertificate", "true")
+	}
+	if opts.TLS.CAFile != "" {
+		params.Set("certificate", opts.TLS.CAFile)
 	}
+	if opts.TLS.ServerName != "" {
+		params.Set("hostnameincertificate", opts.TLS.ServerName)
+	}
+	if opts.ConnectTimeout > 0 {
+		params.Set("dial timeout", strconv.Itoa(int(math.Ceil(opts.ConnectTimeout.Seconds()))))
+	}
+	if opts.AppName != "" {
+		params.Set("app name", opts.AppName)
+	}
+	return params, nil
+}
+
+// sqlServerTx 记录事务及嵌套深度, 用于生成保存点名称
+type sqlServerTx struct {
+	*sql.Tx
+	depth int
+}
+
+// sqlQuerier 是 *sql.DB 和 *sql.Tx 共有的查询方法
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
 
-	clauses := make([]string, 0, len(filter))
-	values := make([]interface{}, 0, len(filter))
+// querier 返回 ctx 中的事务, 没有事务时返回连接池. 使用结束后调用 release
+func (s *SQLServer) querier(ctx context.Context) (q sqlQuerier, release func(), err error) {
+	if tx, ok := txFromContext(ctx, s).(*sqlServerTx); ok {
+		return tx, func() {}, nil
+	}
+	return s.db.acquire()
+}
 
-	i := 0
-	for col, val := range filter {
-		clauses = append(clauses, fmt.Sprintf("%s = @p%d", col, i))
-		values = append(values, val)
-		i++
+func sqlIsoLevel(level IsolationLevel) sql.IsolationLevel {
+	switch level {
+	case LevelReadUncommitted:
+		return sql.LevelReadUncommitted
+	case LevelReadCommitted:
+		return sql.LevelReadCommitted
+	case LevelRepeatableRead:
+		return sql.LevelRepeatableRead
+	case LevelSnapshot:
+		return sql.LevelSnapshot
+	case LevelSerializable:
+		return sql.LevelSerializable
 	}
+	return sql.LevelDefault
+}
 
-	return joinStrings(clauses, " AND "), values
+func sqlServerRetryable(err error) bool {
+	var msErr mssql.Error
+	if errors.As(err, &msErr) {
+		return msErr.Number == 1205 || msErr.Number == 3960
+	}
+	return false
 }
 
-// scanRows 扫描结果集到目标结构
+// scanRows 扫描结果集到目标切片, 支持 *[]T, *[]*T 和 *[]map[string]interface{}.
+// 列按 db 标签映射到字段, 没有标签时使用 snake_case 字段名
 func scanRows(rows *sql.Rows, dest interface{}) error {
-	// 这里需要根据dest的类型来实现具体的扫描逻辑
-	// 可以使用反射来处理不同类型的dest
-	return nil
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	return scanAll(rows, columns, dest)
+}
+
+// sqlServerConnRetryable 判断连接错误是否可以重试, 登录失败和数据库无法打开不重试
+func sqlServerConnRetryable(err error) bool {
+	var msErr mssql.Error
+	if errors.As(err, &msErr) {
+		switch msErr.Number {
+		case 18456, 18470, 18486, 18487, 18488, 4060:
+			return false
+		}
+	}
+	return true
 }
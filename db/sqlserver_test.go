@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+var txUser = map[string]interface{}{"name": "a"}
+
+func TestSQLServerWithTx(t *testing.T) {
+	ctx := context.Background()
+	errInner := errors.New("inner failed")
+
+	tests := []struct {
+		name    string
+		fn      func(s *SQLServer) func(ctx context.Context) error
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "commit",
+			fn: func(s *SQLServer) func(ctx context.Context) error {
+				return func(ctx context.Context) error { return s.Create(ctx, "users", txUser) }
+			},
+			want: []string{"BEGIN", "INSERT INTO [users] ([name]) VALUES (@p1) [a]", "COMMIT"},
+		},
+		{
+			name: "rollback",
+			fn: func(s *SQLServer) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					if err := s.Create(ctx, "users", txUser); err != nil {
+						return err
+					}
+					return errInner
+				}
+			},
+			wantErr: errInner,
+			want:    []string{"BEGIN", "INSERT INTO [users] ([name]) VALUES (@p1) [a]", "ROLLBACK"},
+		},
+		{
+			name: "savepoint rollback",
+			fn: func(s *SQLServer) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					err := s.WithTx(ctx, func(ctx context.Context) error {
+						if err := s.Create(ctx, "users", txUser); err != nil {
+							return err
+						}
+						return errInner
+					})
+					if !errors.Is(err, errInner) {
+						t.Errorf("inner WithTx: got %v, want %v", err, errInner)
+					}
+					return nil
+				}
+			},
+			want: []string{
+				"BEGIN",
+				"SAVE TRANSACTION sp_1",
+				"INSERT INTO [users] ([name]) VALUES (@p1) [a]",
+				"ROLLBACK TRANSACTION sp_1",
+				"COMMIT",
+			},
+		},
+		{
+			name: "savepoint commit",
+			fn: func(s *SQLServer) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return s.WithTx(ctx, func(ctx context.Context) error {
+						return s.WithTx(ctx, func(ctx context.Context) error {
+							return s.Create(ctx, "users", txUser)
+						})
+					})
+				}
+			},
+			want: []string{
+				"BEGIN",
+				"SAVE TRANSACTION sp_1",
+				"SAVE TRANSACTION sp_2",
+				"INSERT INTO [users] ([name]) VALUES (@p1) [a]",
+				"COMMIT",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake := newFakeSQLServer(t)
+			err := s.WithTx(ctx, tt.fn(s))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WithTx: got %v, want %v", err, tt.wantErr)
+			}
+			if got := fake.log(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLServerWithTxRollsBackOnPanic(t *testing.T) {
+	s, fake := newFakeSQLServer(t)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("WithTx swallowed the panic")
+			}
+		}()
+		_ = s.WithTx(context.Background(), func(ctx context.Context) error { panic("boom") })
+	}()
+	if got, want := fake.log(), []string{"BEGIN", "ROLLBACK"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func TestSQLServerWithTxRetriesDeadlocks(t *testing.T) {
+	s, fake := newFakeSQLServer(t)
+	failed := false
+	fake.fail = func(query string) error {
+		if strings.HasPrefix(query, "INSERT") && !failed {
+			failed = true
+			return mssql.Error{Number: 1205, Message: "deadlock"}
+		}
+		return nil
+	}
+
+	err := s.WithTx(context.Background(), func(ctx context.Context) error {
+		return s.Create(ctx, "users", txUser)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BEGIN", "INSERT INTO [users] ([name]) VALUES (@p1) [a]", "ROLLBACK",
+		"BEGIN", "INSERT INTO [users] ([name]) VALUES (@p1) [a]", "COMMIT",
+	}
+	if got := fake.log(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestSQLServerWithTxReadOnlyUnsupported(t *testing.T) {
+	s, fake := newFakeSQLServer(t)
+	called := false
+	err := s.WithTx(context.Background(), func(ctx context.Context) error {
+		called = true
+		return nil
+	}, WithReadOnly())
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("got %v, want errors.ErrUnsupported", err)
+	}
+	if called || len(fake.log()) != 0 {
+		t.Error("read-only transaction should fail before starting")
+	}
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dankko0w0/gospike/db/retry"
+)
+
+// Transactor 由支持事务的驱动实现.
+// fn 收到的 ctx 携带事务, 用它调用同一驱动的 DataOperation 方法即可参与事务.
+// fn 返回错误或 panic 时回滚, 否则提交. 在事务 ctx 中再次调用 WithTx 时,
+// SQL 驱动使用保存点实现嵌套, 内层失败只回滚到保存点
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+}
+
+// IsolationLevel 事务隔离级别
+type IsolationLevel int
+
+const (
+	// LevelDefault 使用数据库默认隔离级别
+	LevelDefault IsolationLevel = iota
+	LevelReadUncommitted
+	LevelReadCommitted
+	LevelRepeatableRead
+	LevelSnapshot
+	LevelSerializable
+)
+
+func (l IsolationLevel) String() string {
+	switch l {
+	case LevelDefault:
+		return "default"
+	case LevelReadUncommitted:
+		return "read uncommitted"
+	case LevelReadCommitted:
+		return "read committed"
+	case LevelRepeatableRead:
+		return "repeatable read"
+	case LevelSnapshot:
+		return "snapshot"
+	case LevelSerializable:
+		return "serializable"
+	}
+	return fmt.Sprintf("IsolationLevel(%d)", int(l))
+}
+
+// TxOptions 事务选项
+type TxOptions struct {
+	Isolation IsolationLevel
+	ReadOnly  bool
+	// MaxRetries 是序列化失败或死锁时的最大重试次数, 只对最外层事务生效
+	MaxRetries int
+}
+
+// TxOption 修改事务选项
+type TxOption func(*TxOptions)
+
+// WithIsolation 设置隔离级别
+func WithIsolation(level IsolationLevel) TxOption {
+	return func(o *TxOptions) { o.Isolation = level }
+}
+
+// WithReadOnly 开启只读事务. SQL Server 不支持, WithTx 返回 errors.ErrUnsupported
+func WithReadOnly() TxOption {
+	return func(o *TxOptions) { o.ReadOnly = true }
+}
+
+// WithMaxRetries 设置序列化失败时的重试次数, 0 表示不重试
+func WithMaxRetries(n int) TxOption {
+	return func(o *TxOptions) { o.MaxRetries = n }
+}
+
+const defaultTxRetries = 3
+
+func newTxOptions(opts []TxOption) TxOptions {
+	o := TxOptions{MaxRetries: defaultTxRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// txKey 区分不同驱动实例的事务, 避免把一个连接的事务用到另一个连接上
+type txKey struct {
+	owner interface{}
+}
+
+func contextWithTx(ctx context.Context, owner, tx interface{}) context.Context {
+	return context.WithValue(ctx, txKey{owner}, tx)
+}
+
+func txFromContext(ctx context.Context, owner interface{}) interface{} {
+	return ctx.Value(txKey{owner})
+}
+
+// retryTx 执行 attempt, 在 retryable 判断为可重试时以短退避最多重试 maxRetries 次
+func retryTx(ctx context.Context, driver string, maxRetries int, retryable func(error) bool, attempt func() error) error {
+	policy := retry.Policy{
+		MaxAttempts:     maxRetries + 1,
+		InitialInterval: 10 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+		Retryable:       retryable,
+		OnRetry: func(n int, err error, delay time.Duration) {
+			log := dbLogger(ctx, driver)
+			log.Debug().Err(err).Int("attempt", n).Dur("delay", delay).Msg("retrying transaction")
+		},
+	}
+	return policy.Do(ctx, func(context.Context) error { return attempt() })
+}
+
+// runTx 执行 fn 并根据结果提交或回滚, fn panic 时回滚后继续 panic
+func runTx(ctx context.Context, fn func(ctx context.Context) error, commit, rollback func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			_ = rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(ctx); err != nil {
+		if rbErr := rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return commit()
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultRedactedHeaders are the headers whose values are never logged
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// AccessLogOptions configures the HTTP and gRPC access loggers
+type AccessLogOptions struct {
+	// Module tags the access logs; defaults to "access"
+	Module string
+	// SuccessSampling logs one in every N successful requests; 0 or 1 logs all
+	SuccessSampling uint32
+	// SlowThreshold logs requests slower than this at warn level, bypassing sampling
+	SlowThreshold time.Duration
+	// RequestIDHeader is read and echoed back; defaults to "X-Request-ID"
+	RequestIDHeader string
+	// LogHeaders logs the request headers
+	LogHeaders bool
+	// RedactHeaders replaces DefaultRedactedHeaders when not empty
+	RedactHeaders []string
+}
+
+func (o AccessLogOptions) withDefaults() AccessLogOptions {
+	if o.Module == "" {
+		o.Module = "access"
+	}
+	if o.RequestIDHeader == "" {
+		o.RequestIDHeader = "X-Request-ID"
+	}
+	if len(o.RedactHeaders) == 0 {
+		o.RedactHeaders = DefaultRedactedHeaders
+	}
+	return o
+}
+
+// accessLogger holds the state shared by the HTTP and gRPC access loggers
+type accessLogger struct {
+	opts     AccessLogOptions
+	redact   map[string]bool
+	requests atomic.Uint32
+}
+
+func newAccessLogger(opts AccessLogOptions) *accessLogger {
+	opts = opts.withDefaults()
+	redact := make(map[string]bool, len(opts.RedactHeaders))
+	for _, h := range opts.RedactHeaders {
+		redact[strings.ToLower(h)] = true
+	}
+	return &accessLogger{opts: opts, redact: redact}
+}
+
+// event returns the event to log a finished request on, or nil when it is
+// sampled out
+func (a *accessLogger) event(l *zerolog.Logger, failed bool, clientError bool, latency time.Duration) *zerolog.Event {
+	slow := a.opts.SlowThreshold > 0 && latency >= a.opts.SlowThreshold
+
+	switch {
+	case failed:
+		return l.Error()
+	case clientError || slow:
+		e := l.Warn()
+		if slow {
+			e = e.Bool("slow", true)
+		}
+		return e
+	}
+
+	if n := a.opts.SuccessSampling; n > 1 && a.requests.Add(1)%n != 1 {
+		return nil
+	}
+	return l.Info()
+}
+
+// headers returns the request headers with sensitive values redacted
+func (a *accessLogger) headers(h http.Header) map[string]interface{} {
+	headers := make(map[string]interface{}, len(h))
+	for name, values := range h {
+		if a.redact[strings.ToLower(name)] {
+			headers[name] = "[REDACTED]"
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// maxRequestIDLength caps the length of inbound request ids
+const maxRequestIDLength = 128
+
+// inboundRequestID returns the inbound request id, or a new one when it is
+// missing, too long or contains characters other than letters, digits and
+// "-._:", so clients cannot inject headers or log lines through it
+func inboundRequestID(inbound string) string {
+	if inbound == "" || len(inbound) > maxRequestIDLength {
+		return newRequestID()
+	}
+	for _, c := range inbound {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_', c == ':':
+		default:
+			return newRequestID()
+		}
+	}
+	return inbound
+}
+
+// newRequestID generates a random request id
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// parseTraceparent extracts the trace and span ids from a W3C traceparent header
+func parseTraceparent(v string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+	"github.com/rs/zerolog"
+)
+
+// Backpressure policies of the asynchronous writer
+const (
+	PolicyBlock      = "block"       // wait for room in the buffer
+	PolicyDropNewest = "drop_newest" // discard the incoming event
+	PolicyDropOldest = "drop_oldest" // discard the oldest buffered event
+)
+
+const (
+	defaultAsyncBuffer = 4096
+	// asyncFatalFlushTimeout bounds how long a fatal or panic event waits
+	// for the buffered events before it is written
+	asyncFatalFlushTimeout = 5 * time.Second
+)
+
+// Dropped returns the number of events discarded because the buffer of
+// the asynchronous writer of the current configuration was full
+func Dropped() uint64 {
+	var n uint64
+	for _, c := range output.outputs() {
+		if a, ok := c.(*asyncWriter); ok {
+			n += a.dropped.Load()
+		}
+	}
+	return n
+}
+
+type asyncEntry struct {
+	level zerolog.Level
+	p     []byte
+}
+
+// asyncWriter queues events in a ring buffer and writes them to w from a
+// background goroutine
+type asyncWriter struct {
+	w       zerolog.LevelWriter
+	policy  string
+	dropped atomic.Uint64
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	ring     []asyncEntry
+	head     int
+	n        int
+	closed   bool
+
+	// enqueued and written count events so Flush can wait for the ones
+	// queued before it was called
+	enqueued uint64
+	written  uint64
+	progress chan struct{}
+	done     chan struct{}
+}
+
+func newAsyncWriter(w zerolog.LevelWriter, cfg models.AsyncConfig) *asyncWriter {
+	size := cfg.BufferSize
+	if size <= 0 {
+		size = defaultAsyncBuffer
+	}
+	policy := cfg.Policy
+	if policy == "" {
+		policy = PolicyDropNewest
+	}
+
+	a := &asyncWriter{
+		w:        w,
+		policy:   policy,
+		ring:     make([]asyncEntry, size),
+		progress: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	a.notEmpty = sync.NewCond(&a.mu)
+	a.notFull = sync.NewCond(&a.mu)
+	go a.run()
+	return a
+}
+
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	return a.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (a *asyncWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	// Fatal 和 Panic 之后进程会退出, 先写完缓冲的日志再同步写入
+	if level == zerolog.FatalLevel || level == zerolog.PanicLevel {
+		ctx, cancel := context.WithTimeout(context.Background(), asyncFatalFlushTimeout)
+		a.Flush(ctx)
+		cancel()
+		return a.w.WriteLevel(level, p)
+	}
+
+	// zerolog 会复用 p, 必须拷贝
+	entry := asyncEntry{level: level, p: append([]byte(nil), p...)}
+
+	a.mu.Lock()
+	for a.n == len(a.ring) && a.policy == PolicyBlock && !a.closed {
+		a.notFull.Wait()
+	}
+	if a.closed {
+		a.mu.Unlock()
+		return a.w.WriteLevel(level, p)
+	}
+
+	if a.n == len(a.ring) {
+		a.dropped.Add(1)
+		if a.policy != PolicyDropOldest {
+			a.mu.Unlock()
+			return len(p), nil
+		}
+		a.head = (a.head + 1) % len(a.ring)
+		a.n--
+		a.written++
+	}
+	a.ring[(a.head+a.n)%len(a.ring)] = entry
+	a.n++
+	a.enqueued++
+	a.notEmpty.Signal()
+	a.mu.Unlock()
+	return len(p), nil
+}
+
+func (a *asyncWriter) run() {
+	defer close(a.done)
+
+	var batch []asyncEntry
+	for {
+		a.mu.Lock()
+		for a.n == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+		if a.n == 0 {
+			a.mu.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for ; a.n > 0; a.n-- {
+			batch = append(batch, a.ring[a.head])
+			a.ring[a.head] = asyncEntry{}
+			a.head = (a.head + 1) % len(a.ring)
+		}
+		a.notFull.Broadcast()
+		a.mu.Unlock()
+
+		for _, e := range batch {
+			a.w.WriteLevel(e.level, e.p)
+		}
+
+		a.mu.Lock()
+		a.written += uint64(len(batch))
+		close(a.progress)
+		a.progress = make(chan struct{})
+		a.mu.Unlock()
+	}
+}
+
+// Flush waits until the events queued before the call have been written
+func (a *asyncWriter) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	target := a.enqueued
+	for a.written < target {
+		progress := a.progress
+		a.mu.Unlock()
+		select {
+		case <-progress:
+		case <-a.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		a.mu.Lock()
+	}
+	a.mu.Unlock()
+	return nil
+}
+
+// Close drains the buffer and stops the background goroutine. Later
+// writes go straight to the underlying writer.
+func (a *asyncWriter) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+	return nil
+}
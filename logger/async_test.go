@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+	"github.com/rs/zerolog"
+)
+
+// slowWriter records events after a delay
+type slowWriter struct {
+	mu     sync.Mutex
+	levels []zerolog.Level
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *slowWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.levels = append(w.levels, level)
+	return len(p), nil
+}
+
+func TestAsyncWriterWritesFatalAfterBuffered(t *testing.T) {
+	out := &slowWriter{}
+	a := newAsyncWriter(out, models.AsyncConfig{BufferSize: 64, Policy: PolicyBlock})
+	defer a.Close()
+
+	for i := 0; i < 20; i++ {
+		a.WriteLevel(zerolog.InfoLevel, []byte(`{}`))
+	}
+	a.WriteLevel(zerolog.FatalLevel, []byte(`{}`))
+
+	// 不调用 Flush: fatal 事件返回时必须已经写出
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if n := len(out.levels); n != 21 || out.levels[n-1] != zerolog.FatalLevel {
+		t.Fatalf("got %d events ending in %v, want 20 info events then fatal", n, out.levels)
+	}
+}
+
+func TestAsyncWriterDroppedPerWriter(t *testing.T) {
+	block := make(chan struct{})
+	blocked := zerolog.LevelWriterAdapter{Writer: writerFunc(func(p []byte) (int, error) {
+		<-block
+		return len(p), nil
+	})}
+	full := newAsyncWriter(blocked, models.AsyncConfig{BufferSize: 1})
+	other := newAsyncWriter(&slowWriter{}, models.AsyncConfig{BufferSize: 1})
+	defer other.Close()
+
+	for i := 0; i < 10; i++ {
+		full.WriteLevel(zerolog.InfoLevel, []byte(`{}`))
+	}
+	if full.dropped.Load() == 0 {
+		t.Error("full writer dropped nothing")
+	}
+	if n := other.dropped.Load(); n != 0 {
+		t.Errorf("other writer dropped %d events", n)
+	}
+	close(block)
+	full.Flush(context.Background())
+	full.Close()
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,250 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Dankko0w0/gospike/confManager"
+	"github.com/Dankko0w0/gospike/models"
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+var watched sync.Map
+
+// InitFromConfig builds a logger from cfg and replaces the current one.
+// It may be called again at any time to apply a new configuration.
+func InitFromConfig(cfg models.LoggerConfig) error {
+	level, err := parseLevel(cfg.Level, zerolog.DebugLevel)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(cfg.Format) {
+	case "", FormatConsole, FormatJSON:
+	default:
+		return fmt.Errorf("invalid log format %q", cfg.Format)
+	}
+	switch cfg.Async.Policy {
+	case "", PolicyBlock, PolicyDropNewest, PolicyDropOldest:
+	default:
+		return fmt.Errorf("invalid async policy %q", cfg.Async.Policy)
+	}
+
+	modules := make(map[string]zerolog.Level, len(cfg.Modules))
+	for module, name := range cfg.Modules {
+		if name == "" {
+			continue
+		}
+		if modules[module], err = parseLevel(name, level); err != nil {
+			return err
+		}
+	}
+
+	sampler, err := newLevelSampler(cfg.Sampling)
+	if err != nil {
+		return err
+	}
+	redactor, err := newRedactorFromConfig(cfg.Redact)
+	if err != nil {
+		return err
+	}
+
+	w, cs, err := buildWriter(cfg)
+	if err != nil {
+		return err
+	}
+	w, cs = wrapWriter(w, cs, redactor, cfg.Async)
+
+	output.swap(w, cs)
+	wrapping.Store(&writerWrapping{redactor: redactor, async: cfg.Async})
+	applySampling(sampler, cfg.Dedup)
+	applyConfiguredLevels(level, modules)
+	return nil
+}
+
+// InitFromConfManager reads the logger configuration under key from
+// confManager and re-applies it whenever the config file is reloaded.
+func InitFromConfManager(key string) error {
+	if !confManager.ConfInitialized {
+		return fmt.Errorf("config manager is not initialized")
+	}
+
+	if err := loadFromConfManager(key); err != nil {
+		return err
+	}
+
+	if _, loaded := watched.LoadOrStore(key, struct{}{}); !loaded {
+		confManager.OnChange(func() {
+			if err := loadFromConfManager(key); err != nil {
+				Error("failed to reload logger config", err)
+				return
+			}
+			Info("logger config reloaded")
+		})
+	}
+	return nil
+}
+
+func loadFromConfManager(key string) error {
+	var cfg models.LoggerConfig
+	if err := confManager.UnmarshalKey(key, &cfg); err != nil {
+		return fmt.Errorf("failed to decode logger config %q: %w", key, err)
+	}
+	return InitFromConfig(cfg)
+}
+
+// writerWrapping records how the last configuration wraps its outputs, so
+// outputs installed later, such as UseSlogHandler's, are wrapped the same way
+type writerWrapping struct {
+	redactor *Redactor
+	async    models.AsyncConfig
+}
+
+var wrapping atomic.Pointer[writerWrapping]
+
+// currentWrapping returns the wrapping of the last configuration, or the
+// default redaction when none has been applied
+func currentWrapping() *writerWrapping {
+	if ww := wrapping.Load(); ww != nil {
+		return ww
+	}
+	redactor, _ := newRedactorFromConfig(models.RedactConfig{})
+	return &writerWrapping{redactor: redactor}
+}
+
+// wrapWriter applies redaction and asynchronous writing to w
+func wrapWriter(w zerolog.LevelWriter, cs []io.Closer, redactor *Redactor, async models.AsyncConfig) (zerolog.LevelWriter, []io.Closer) {
+	if redactor != nil {
+		w = redactWriter{w: w, r: redactor}
+	}
+	if async.Enabled {
+		aw := newAsyncWriter(w, async)
+		// 先关闭异步写入器, 保证缓冲的日志在输出关闭前写完
+		w, cs = aw, append([]io.Closer{aw}, cs...)
+	}
+	return w, cs
+}
+
+// buildWriter creates the configured outputs, each filtered by its own level
+func buildWriter(cfg models.LoggerConfig) (zerolog.LevelWriter, []io.Closer, error) {
+	var writers []io.Writer
+	var cs []io.Closer
+
+	if cfg.LogToConsole {
+		level, err := parseLevel(cfg.ConsoleLevel, zerolog.TraceLevel)
+		if err != nil {
+			return nil, nil, err
+		}
+		writers = append(writers, filtered(consoleWriter(cfg), level))
+	}
+
+	if cfg.LogToFile {
+		if cfg.LogFilePath == "" {
+			return nil, nil, fmt.Errorf("logFilePath is required when logToFile is enabled")
+		}
+		level, err := parseLevel(cfg.FileLevel, zerolog.TraceLevel)
+		if err != nil {
+			return nil, nil, err
+		}
+		file, err := fileWriter(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		writers = append(writers, filtered(file, level))
+		cs = append(cs, file)
+	}
+
+	for _, sinkCfg := range cfg.Sinks {
+		level, err := parseLevel(sinkCfg.Level, zerolog.TraceLevel)
+		if err != nil {
+			closeAll(cs)
+			return nil, nil, err
+		}
+		sink, err := newSink(sinkCfg)
+		if err != nil {
+			closeAll(cs)
+			return nil, nil, err
+		}
+		writers = append(writers, filtered(sink, level))
+		if c, ok := sink.(io.Closer); ok {
+			cs = append(cs, c)
+		}
+	}
+
+	return zerolog.MultiLevelWriter(writers...), cs, nil
+}
+
+// fileWriter uses lumberjack for size-based rotation and rotateWriter when
+// time-based rotation or a total size limit is configured
+func fileWriter(cfg models.LoggerConfig) (io.WriteCloser, error) {
+	if cfg.Rotation.Interval != "" || cfg.Rotation.TimeFormat != "" || cfg.Rotation.MaxTotalSize > 0 {
+		return newRotateWriter(cfg)
+	}
+	return &lumberjack.Logger{
+		Filename:   cfg.LogFilePath,
+		MaxSize:    cfg.MaxFileSize, // megabytes
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAge, // days
+		Compress:   cfg.Compress,
+	}, nil
+}
+
+func consoleWriter(cfg models.LoggerConfig) io.Writer {
+	switch strings.ToLower(cfg.Format) {
+	case FormatJSON:
+		return os.Stdout
+	}
+
+	consoleWriter := zerolog.ConsoleWriter{Out: os.Stdout}
+
+	// 自定义控制台打印格式
+	consoleFormat := cfg.ConsoleFormat
+	if consoleFormat == nil {
+		defaultConsoleFormat := models.DefaultConsoleFormat()
+		consoleFormat = &defaultConsoleFormat
+	}
+	consoleWriter.TimeFormat = consoleFormat.TimeFormat
+	consoleWriter.NoColor = consoleFormat.NoColor
+	if len(consoleFormat.PartsOrder) > 0 {
+		consoleWriter.PartsOrder = consoleFormat.PartsOrder
+	}
+	if len(consoleFormat.PartsExclude) > 0 {
+		consoleWriter.PartsExclude = consoleFormat.PartsExclude
+	}
+	return consoleWriter
+}
+
+func filtered(w io.Writer, level zerolog.Level) io.Writer {
+	lw, ok := w.(zerolog.LevelWriter)
+	if !ok {
+		lw = zerolog.LevelWriterAdapter{Writer: w}
+	}
+	return &zerolog.FilteredLevelWriter{Writer: lw, Level: level}
+}
+
+func closeAll(cs []io.Closer) {
+	for _, c := range cs {
+		c.Close()
+	}
+}
+
+// parseLevel parses a level name, returning def when name is empty
+func parseLevel(name string, def zerolog.Level) (zerolog.Level, error) {
+	if name == "" {
+		return def, nil
+	}
+	level, err := zerolog.ParseLevel(strings.ToLower(name))
+	if err != nil {
+		return def, fmt.Errorf("invalid log level %q: %w", name, err)
+	}
+	return level, nil
+}
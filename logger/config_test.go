@@ -0,0 +1,22 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/Dankko0w0/gospike/models"
+)
+
+func TestInitFromConfigRejectsInvalidValues(t *testing.T) {
+	tests := map[string]models.LoggerConfig{
+		"level":  {Level: "loud"},
+		"format": {Format: "xml", LogToConsole: true},
+		"async":  {Async: models.AsyncConfig{Policy: "maybe"}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := InitFromConfig(cfg); err == nil {
+				t.Fatalf("InitFromConfig(%+v) succeeded", cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// Field names added by FromContext
+var (
+	RequestIDFieldName = "request_id"
+	UserIDFieldName    = "user_id"
+	TraceIDFieldName   = "trace_id"
+	SpanIDFieldName    = "span_id"
+)
+
+type loggerKey struct{}
+
+type fieldsKey struct{}
+
+// contextFields holds the request-scoped values carried by a context
+type contextFields struct {
+	requestID string
+	userID    string
+	traceID   string
+	spanID    string
+}
+
+// WithContext returns a copy of ctx carrying l, which FromContext returns
+// instead of the global logger
+func WithContext(ctx context.Context, l zerolog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or the global logger,
+// with the request-scoped fields of ctx attached
+func FromContext(ctx context.Context) zerolog.Logger {
+	l, ok := ctx.Value(loggerKey{}).(zerolog.Logger)
+	if !ok {
+		l = Logger()
+	}
+	return Enrich(ctx, l)
+}
+
+// Enrich attaches the request-scoped fields of ctx to l
+func Enrich(ctx context.Context, l zerolog.Logger) zerolog.Logger {
+	f, ok := ctx.Value(fieldsKey{}).(contextFields)
+	if !ok {
+		return l
+	}
+
+	c := l.With()
+	if f.requestID != "" {
+		c = c.Str(RequestIDFieldName, f.requestID)
+	}
+	if f.userID != "" {
+		c = c.Str(UserIDFieldName, f.userID)
+	}
+	if f.traceID != "" {
+		c = c.Str(TraceIDFieldName, f.traceID)
+	}
+	if f.spanID != "" {
+		c = c.Str(SpanIDFieldName, f.spanID)
+	}
+	return c.Logger()
+}
+
+// WithRequestID returns a copy of ctx carrying the request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	f := fieldsFrom(ctx)
+	f.requestID = id
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// WithUserID returns a copy of ctx carrying the user id
+func WithUserID(ctx context.Context, id string) context.Context {
+	f := fieldsFrom(ctx)
+	f.userID = id
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// WithTrace returns a copy of ctx carrying the trace and span ids
+func WithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	f := fieldsFrom(ctx)
+	f.traceID, f.spanID = traceID, spanID
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// RequestID returns the request id carried by ctx
+func RequestID(ctx context.Context) string {
+	return fieldsFrom(ctx).requestID
+}
+
+// UserID returns the user id carried by ctx
+func UserID(ctx context.Context) string {
+	return fieldsFrom(ctx).userID
+}
+
+// Trace returns the trace and span ids carried by ctx
+func Trace(ctx context.Context) (traceID, spanID string) {
+	f := fieldsFrom(ctx)
+	return f.traceID, f.spanID
+}
+
+func fieldsFrom(ctx context.Context) contextFields {
+	f, _ := ctx.Value(fieldsKey{}).(contextFields)
+	return f
+}
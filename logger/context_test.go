@@ -0,0 +1,122 @@
+package logger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/logger"
+	"github.com/Dankko0w0/gospike/logger/loggertest"
+	"github.com/rs/zerolog"
+)
+
+func TestFromContextFallsBackToGlobalLogger(t *testing.T) {
+	rec := loggertest.New(t)
+
+	l := logger.FromContext(context.Background())
+	l.Info().Msg("bare")
+
+	e := rec.AssertLogged(t, loggertest.Message("bare"), loggertest.Level(zerolog.InfoLevel))
+	for _, key := range []string{logger.RequestIDFieldName, logger.UserIDFieldName, logger.TraceIDFieldName, logger.SpanIDFieldName} {
+		if _, ok := e.Fields[key]; ok {
+			t.Errorf("bare context added %s: %s", key, e.Raw)
+		}
+	}
+}
+
+func TestFromContextFields(t *testing.T) {
+	rec := loggertest.New(t)
+
+	ctx := logger.WithRequestID(context.Background(), "req-1")
+	ctx = logger.WithUserID(ctx, "user-1")
+	ctx = logger.WithTrace(ctx, "trace-1", "span-1")
+
+	l := logger.FromContext(ctx)
+	l.Info().Msg("enriched")
+
+	rec.AssertLogged(t,
+		loggertest.Message("enriched"),
+		loggertest.Field(logger.RequestIDFieldName, "req-1"),
+		loggertest.Field(logger.UserIDFieldName, "user-1"),
+		loggertest.Field(logger.TraceIDFieldName, "trace-1"),
+		loggertest.Field(logger.SpanIDFieldName, "span-1"),
+	)
+
+	if got := logger.RequestID(ctx); got != "req-1" {
+		t.Errorf("RequestID() = %q, want req-1", got)
+	}
+	if got := logger.UserID(ctx); got != "user-1" {
+		t.Errorf("UserID() = %q, want user-1", got)
+	}
+	if traceID, spanID := logger.Trace(ctx); traceID != "trace-1" || spanID != "span-1" {
+		t.Errorf("Trace() = %q, %q, want trace-1, span-1", traceID, spanID)
+	}
+}
+
+func TestFromContextDerivedContexts(t *testing.T) {
+	rec := loggertest.New(t)
+
+	parent := logger.WithRequestID(context.Background(), "req-1")
+	child := logger.WithTrace(parent, "trace-1", "span-2")
+	child = logger.WithRequestID(child, "req-2")
+
+	// 子 context 的修改不影响父 context
+	parentLogger := logger.FromContext(parent)
+	parentLogger.Info().Msg("parent")
+	childLogger := logger.FromContext(child)
+	childLogger.Info().Msg("child")
+
+	rec.AssertLogged(t, loggertest.Message("parent"), loggertest.Field(logger.RequestIDFieldName, "req-1"))
+	rec.AssertNotLogged(t, loggertest.Message("parent"), loggertest.HasField(logger.TraceIDFieldName))
+	rec.AssertLogged(t,
+		loggertest.Message("child"),
+		loggertest.Field(logger.RequestIDFieldName, "req-2"),
+		loggertest.Field(logger.TraceIDFieldName, "trace-1"),
+		loggertest.Field(logger.SpanIDFieldName, "span-2"),
+	)
+}
+
+func TestWithContextLogger(t *testing.T) {
+	global := loggertest.New(t)
+	rec := loggertest.NewRecorder()
+
+	ctx := logger.WithContext(context.Background(), zerolog.New(rec).With().Str("component", "worker").Logger())
+	ctx = logger.WithRequestID(ctx, "req-1")
+
+	l := logger.FromContext(ctx)
+	l.Info().Msg("stored")
+
+	rec.AssertLogged(t,
+		loggertest.Message("stored"),
+		loggertest.Field("component", "worker"),
+		loggertest.Field(logger.RequestIDFieldName, "req-1"),
+	)
+	global.AssertNotLogged(t, loggertest.Message("stored"))
+}
+
+func TestEnrich(t *testing.T) {
+	rec := loggertest.NewRecorder()
+	base := zerolog.New(rec)
+
+	l := logger.Enrich(context.Background(), base)
+	l.Info().Msg("unchanged")
+	rec.AssertNotLogged(t, loggertest.Message("unchanged"), loggertest.HasField(logger.RequestIDFieldName))
+
+	ctx := logger.WithTrace(context.Background(), "trace-1", "")
+	l = logger.Enrich(ctx, base)
+	l.Info().Msg("trace only")
+	rec.AssertLogged(t, loggertest.Message("trace only"), loggertest.Field(logger.TraceIDFieldName, "trace-1"))
+	rec.AssertNotLogged(t, loggertest.Message("trace only"), loggertest.HasField(logger.SpanIDFieldName))
+	rec.AssertNotLogged(t, loggertest.Message("trace only"), loggertest.HasField(logger.RequestIDFieldName))
+}
+
+func TestFieldNamesAreConfigurable(t *testing.T) {
+	rec := loggertest.New(t)
+	prev := logger.RequestIDFieldName
+	logger.RequestIDFieldName = "rid"
+	t.Cleanup(func() { logger.RequestIDFieldName = prev })
+
+	l := logger.FromContext(logger.WithRequestID(context.Background(), "req-1"))
+	l.Info().Msg("renamed")
+
+	rec.AssertLogged(t, loggertest.Message("renamed"), loggertest.Field("rid", "req-1"))
+}
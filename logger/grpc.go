@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor that logs every unary
+// call, including the encoded sizes of the request and response
+func UnaryServerInterceptor(opts AccessLogOptions) grpc.UnaryServerInterceptor {
+	a := newAccessLogger(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx = a.grpcContext(ctx)
+
+		resp, err := handler(ctx, req)
+		stats := callStats{bytesReceived: messageSize(req)}
+		if err == nil {
+			stats.bytesSent = messageSize(resp)
+		}
+		a.logCall(ctx, info.FullMethod, err, time.Since(start), stats)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that logs every
+// stream, including the number and total encoded size of the messages
+func StreamServerInterceptor(opts AccessLogOptions) grpc.StreamServerInterceptor {
+	a := newAccessLogger(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		stream := &serverStream{ServerStream: ss, ctx: a.grpcContext(ss.Context())}
+
+		err := handler(srv, stream)
+		a.logCall(stream.ctx, info.FullMethod, err, time.Since(start), stream.callStats)
+		return err
+	}
+}
+
+// grpcContext attaches the request and trace ids from the incoming metadata
+func (a *accessLogger) grpcContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := inboundRequestID(first(md.Get(a.opts.RequestIDHeader)))
+	ctx = WithRequestID(ctx, requestID)
+	if traceID, spanID, ok := parseTraceparent(first(md.Get("traceparent"))); ok {
+		ctx = WithTrace(ctx, traceID, spanID)
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(a.opts.RequestIDHeader, requestID))
+	return ctx
+}
+
+// callStats counts the messages of a call. Unary calls leave the message
+// counts at zero.
+type callStats struct {
+	sent          int
+	received      int
+	bytesSent     int
+	bytesReceived int
+}
+
+func (a *accessLogger) logCall(ctx context.Context, method string, err error, latency time.Duration, stats callStats) {
+	code := status.Code(err)
+	l := Enrich(ctx, Module(a.opts.Module))
+	e := a.event(&l, isServerError(code), code != codes.OK, latency)
+	if e == nil {
+		return
+	}
+	if a.opts.LogHeaders {
+		md, _ := metadata.FromIncomingContext(ctx)
+		e = e.Fields(map[string]interface{}{"headers": a.headers(http.Header(md))})
+	}
+	if stats.sent > 0 || stats.received > 0 {
+		e = e.Int("msgs_sent", stats.sent).Int("msgs_received", stats.received)
+	}
+	e.Str("method", method).
+		Int("bytes_received", stats.bytesReceived).
+		Int("bytes_sent", stats.bytesSent).
+		Str("code", code.String()).
+		Dur("latency", latency).
+		Err(err).
+		Msg("grpc request")
+}
+
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal,
+		codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// messageSize returns the encoded size of a protobuf message. Messages of
+// other codecs are counted as 0 bytes.
+func messageSize(m interface{}) int {
+	if pm, ok := m.(proto.Message); ok {
+		return proto.Size(pm)
+	}
+	return 0
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// serverStream overrides the stream context and counts messages
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	callStats
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		s.bytesSent += messageSize(m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+		s.bytesReceived += messageSize(m)
+	}
+	return err
+}
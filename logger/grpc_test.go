@@ -0,0 +1,160 @@
+package logger_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/logger"
+	"github.com/Dankko0w0/gospike/logger/loggertest"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// echoService echoes StringValue messages over a unary and a bidi stream method
+var echoService = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Unary",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := new(wrapperspb.StringValue)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				v := req.(*wrapperspb.StringValue).Value
+				if v == "fail" {
+					return nil, status.Error(codes.Internal, "failed")
+				}
+				return wrapperspb.String(v + v), nil
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Unary"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			for {
+				msg := new(wrapperspb.StringValue)
+				if err := stream.RecvMsg(msg); errors.Is(err, io.EOF) {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				if err := stream.SendMsg(wrapperspb.String(msg.Value + msg.Value)); err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+func newGRPCClient(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logger.UnaryServerInterceptor(logger.AccessLogOptions{})),
+		grpc.ChainStreamInterceptor(logger.StreamServerInterceptor(logger.AccessLogOptions{})),
+	)
+	srv.RegisterService(&echoService, struct{}{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestUnaryServerInterceptorLogsSizes(t *testing.T) {
+	rec := loggertest.New(t)
+	conn := newGRPCClient(t)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+	req, resp := wrapperspb.String("hello"), new(wrapperspb.StringValue)
+	if err := conn.Invoke(ctx, "/test.Echo/Unary", req, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	e := rec.AssertLogged(t,
+		loggertest.Message("grpc request"),
+		loggertest.Level(zerolog.InfoLevel),
+		loggertest.Field("method", "/test.Echo/Unary"),
+		loggertest.Field("code", "OK"),
+		loggertest.Field(logger.RequestIDFieldName, "req-1"),
+		loggertest.Field("bytes_received", proto.Size(req)),
+		loggertest.Field("bytes_sent", proto.Size(resp)),
+	)
+	if _, ok := e.Fields["msgs_sent"]; ok {
+		t.Errorf("unary call logged message counts: %s", e.Raw)
+	}
+
+	rec.Reset()
+	err := conn.Invoke(context.Background(), "/test.Echo/Unary", wrapperspb.String("fail"), new(wrapperspb.StringValue))
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Invoke() error = %v, want Internal", err)
+	}
+	rec.AssertLogged(t,
+		loggertest.Message("grpc request"),
+		loggertest.Level(zerolog.ErrorLevel),
+		loggertest.Field("code", "Internal"),
+		loggertest.Field("bytes_received", proto.Size(wrapperspb.String("fail"))),
+		loggertest.Field("bytes_sent", 0),
+	)
+}
+
+func TestStreamServerInterceptorLogsSizes(t *testing.T) {
+	rec := loggertest.New(t)
+	conn := newGRPCClient(t)
+
+	stream, err := conn.NewStream(context.Background(), &echoService.Streams[0], "/test.Echo/Stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received, sent int
+	for _, v := range []string{"a", "bcd", "efghij"} {
+		req := wrapperspb.String(v)
+		if err := stream.SendMsg(req); err != nil {
+			t.Fatal(err)
+		}
+		resp := new(wrapperspb.StringValue)
+		if err := stream.RecvMsg(resp); err != nil {
+			t.Fatal(err)
+		}
+		received += proto.Size(req)
+		sent += proto.Size(resp)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.RecvMsg(new(wrapperspb.StringValue)); !errors.Is(err, io.EOF) {
+		t.Fatalf("RecvMsg() error = %v, want EOF", err)
+	}
+
+	rec.AssertLogged(t,
+		loggertest.Message("grpc request"),
+		loggertest.Field("method", "/test.Echo/Stream"),
+		loggertest.Field("code", "OK"),
+		loggertest.Field("msgs_received", 3),
+		loggertest.Field("msgs_sent", 3),
+		loggertest.Field("bytes_received", received),
+		loggertest.Field("bytes_sent", sent),
+	)
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+// LevelState is the JSON body served and accepted by LevelHandler.
+// A module mapped to an empty string drops its override.
+type LevelState struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// LevelHandler returns an http.Handler that reports the current levels on
+// GET and changes them on PUT
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			if err := applyLevelState(r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(currentLevelState())
+	})
+}
+
+func currentLevelState() LevelState {
+	state := LevelState{Level: GetLevel().String()}
+	if levels := ModuleLevels(); len(levels) > 0 {
+		state.Modules = make(map[string]string, len(levels))
+		for module, l := range levels {
+			state.Modules[module] = l.String()
+		}
+	}
+	return state
+}
+
+func applyLevelState(r *http.Request) error {
+	var state LevelState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	// 先校验全部级别, 避免只应用一部分
+	var global zerolog.Level
+	var err error
+	if state.Level != "" {
+		if global, err = parseLevel(state.Level, zerolog.NoLevel); err != nil {
+			return err
+		}
+	}
+	modules := make(map[string]zerolog.Level, len(state.Modules))
+	for module, name := range state.Modules {
+		if name == "" {
+			continue
+		}
+		if modules[module], err = parseLevel(name, zerolog.NoLevel); err != nil {
+			return err
+		}
+	}
+
+	if state.Level != "" {
+		setLevel(global, "http")
+	}
+	for module, name := range state.Modules {
+		if name == "" {
+			clearModuleLevel(module, "http")
+		} else {
+			setModuleLevel(module, modules[module], "http")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPMiddleware returns net/http middleware that logs every request and
+// attaches the request and trace ids to the request context
+func HTTPMiddleware(opts AccessLogOptions) func(http.Handler) http.Handler {
+	a := newAccessLogger(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			ctx := r.Context()
+			requestID := inboundRequestID(r.Header.Get(a.opts.RequestIDHeader))
+			ctx = WithRequestID(ctx, requestID)
+			if traceID, spanID, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+				ctx = WithTrace(ctx, traceID, spanID)
+			}
+			w.Header().Set(a.opts.RequestIDHeader, requestID)
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				// 处理器 panic 时按 500 记录, 再继续 panic 交给 net/http 处理
+				p := recover()
+				status := rw.status
+				if p != nil {
+					status = http.StatusInternalServerError
+				}
+
+				latency := time.Since(start)
+				l := Enrich(ctx, Module(a.opts.Module))
+				e := a.event(&l, status >= http.StatusInternalServerError, status >= http.StatusBadRequest, latency)
+				if e != nil {
+					if a.opts.LogHeaders {
+						e = e.Fields(map[string]interface{}{"headers": a.headers(r.Header)})
+					}
+					if p != nil {
+						e = e.Str("panic", fmt.Sprint(p))
+					}
+					e.Str("method", r.Method).
+						Str("path", r.URL.Path).
+						Int("status", status).
+						Dur("latency", latency).
+						Int64("bytes", rw.bytes).
+						Str("remote_addr", r.RemoteAddr).
+						Msg("http request")
+				}
+
+				if p != nil {
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
+
+// responseWriter records the status code and body size of a response
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("response writer does not support hijacking")
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
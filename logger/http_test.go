@@ -0,0 +1,62 @@
+package logger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/logger"
+	"github.com/Dankko0w0/gospike/logger/loggertest"
+	"github.com/rs/zerolog"
+)
+
+func TestHTTPMiddlewareRequestID(t *testing.T) {
+	loggertest.New(t)
+	h := logger.HTTPMiddleware(logger.AccessLogOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := map[string]bool{
+		"abc-123.x_y:z":          true,
+		"":                       false,
+		"bad\r\nX-Injected: 1":   false,
+		"with space":             false,
+		strings.Repeat("a", 129): false,
+		strings.Repeat("a", 128): true,
+	}
+	for inbound, kept := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Request-ID", inbound)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		got := w.Header().Get("X-Request-ID")
+		if kept && got != inbound {
+			t.Errorf("request id %q was replaced by %q", inbound, got)
+		}
+		if !kept && (got == inbound || len(got) != 32) {
+			t.Errorf("request id %q was not replaced: %q", inbound, got)
+		}
+	}
+}
+
+func TestHTTPMiddlewareLogsPanics(t *testing.T) {
+	rec := loggertest.New(t)
+	h := logger.HTTPMiddleware(logger.AccessLogOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the handler panic", p)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}()
+
+	rec.AssertLogged(t,
+		loggertest.Message("http request"),
+		loggertest.Level(zerolog.ErrorLevel),
+		loggertest.Field("status", 500),
+		loggertest.Field("panic", "boom"))
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Dankko0w0/gospike/models"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// newJournaldSink writes events to systemd-journald using its native
+// protocol. The message becomes MESSAGE, the level PRIORITY, and the other
+// event fields are sent as upper-case journal fields, e.g. request_id as
+// REQUEST_ID. Events larger than a datagram are rejected by journald.
+func newJournaldSink(cfg models.SinkConfig) (io.Writer, error) {
+	if cfg.Network == "" {
+		cfg.Network = "unixgram"
+	}
+	if cfg.Address == "" {
+		cfg.Address = journaldSocket
+	}
+	if _, err := sinkOptions(cfg); err != nil {
+		return nil, err
+	}
+	return newSyslogWriter(cfg, formatJournald)
+}
+
+// formatJournald formats one entry as newline separated KEY=VALUE fields
+func formatJournald(w *syslogWriter, priority int, msg []byte) []byte {
+	fields, text, ok := eventFields(msg)
+	if !ok {
+		fields, text = nil, string(msg)
+	}
+
+	var b []byte
+	b = appendJournalField(b, "MESSAGE", text)
+	b = appendJournalField(b, "PRIORITY", fmt.Sprint(priority%8))
+	b = appendJournalField(b, "SYSLOG_FACILITY", fmt.Sprint(priority/8))
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", w.tag)
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if key := journalFieldName(name); key != "" {
+			b = appendJournalField(b, key, fieldText(fields[name]))
+		}
+	}
+	return b
+}
+
+// appendJournalField appends KEY=VALUE, or the binary form
+// KEY\n<64-bit little-endian length>VALUE when the value contains a newline
+func appendJournalField(b []byte, key, value string) []byte {
+	if !strings.Contains(value, "\n") {
+		return append(append(append(b, key...), '='), value+"\n"...)
+	}
+	b = append(append(b, key...), '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	return append(b, value+"\n"...)
+}
+
+// journalFieldName converts an event field name to a journal field name:
+// upper case letters, digits and underscores, not starting with an
+// underscore or digit, at most 64 characters. It returns "" for names that
+// would clash with the fields set by formatJournald.
+func journalFieldName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	key := strings.TrimLeft(b.String(), "_0123456789")
+	if len(key) > 64 {
+		key = key[:64]
+	}
+	switch key {
+	case "MESSAGE", "PRIORITY", "SYSLOG_FACILITY", "SYSLOG_IDENTIFIER":
+		return ""
+	}
+	return key
+}
@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// ModuleFieldName is the field name used to tag events from a module logger
+var ModuleFieldName = "module"
+
+var (
+	level      atomic.Int32 // zerolog.Level
+	configured atomic.Int32 // level from the last applied config
+	hasConfig  atomic.Bool
+
+	levelsMu          sync.RWMutex
+	moduleLevels      = map[string]zerolog.Level{}
+	configuredModules = map[string]zerolog.Level{}
+)
+
+// levelSampler rejects events below the effective level of its module
+// before they are built, then applies the configured sampling. Loggers are
+// created at trace level and filtered here, so level changes take effect
+// for loggers that were derived earlier. zerolog.DisableSampling also
+// disables this filter.
+type levelSampler struct {
+	module string
+}
+
+func (s levelSampler) Sample(l zerolog.Level) bool {
+	if l != zerolog.NoLevel && l < effectiveLevel(s.module) {
+		return false
+	}
+	return sampling.Sample(l)
+}
+
+// dedupHook discards repeated messages when deduplication is enabled
+type dedupHook struct {
+	module string
+}
+
+func (h dedupHook) Run(e *zerolog.Event, l zerolog.Level, msg string) {
+	if d := dedup.Load(); d != nil && !d.allow(h.module, l, msg) {
+		e.Discard()
+	}
+}
+
+// Module returns a logger tagged with module whose level can be overridden
+// with SetModuleLevel
+func Module(name string) zerolog.Logger {
+	return filteredLogger(name).With().Str(ModuleFieldName, name).Logger()
+}
+
+// filteredLogger derives a logger from root that applies the levels,
+// sampling and deduplication of module
+func filteredLogger(module string) zerolog.Logger {
+	return root.Sample(levelSampler{module: module}).Hook(dedupHook{module: module})
+}
+
+// GetLevel returns the global log level
+func GetLevel() zerolog.Level {
+	return zerolog.Level(level.Load())
+}
+
+// SetLevel changes the global log level
+func SetLevel(l zerolog.Level) {
+	setLevel(l, "api")
+}
+
+// ModuleLevels returns the per-module level overrides
+func ModuleLevels() map[string]zerolog.Level {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+
+	levels := make(map[string]zerolog.Level, len(moduleLevels))
+	for module, l := range moduleLevels {
+		levels[module] = l
+	}
+	return levels
+}
+
+// SetModuleLevel overrides the log level of a module
+func SetModuleLevel(module string, l zerolog.Level) {
+	setModuleLevel(module, l, "api")
+}
+
+// ClearModuleLevel removes the level override of a module so it follows
+// the global level again
+func ClearModuleLevel(module string) {
+	clearModuleLevel(module, "api")
+}
+
+// ResetLevel restores the levels from the last applied configuration
+func ResetLevel() {
+	resetLevels("api")
+}
+
+func effectiveLevel(module string) zerolog.Level {
+	if module != "" {
+		levelsMu.RLock()
+		l, ok := moduleLevels[module]
+		levelsMu.RUnlock()
+		if ok {
+			return l
+		}
+	}
+	return GetLevel()
+}
+
+func setLevel(l zerolog.Level, source string) {
+	from := zerolog.Level(level.Swap(int32(l)))
+	if from != l {
+		logChange("", from, l, source)
+	}
+}
+
+func setModuleLevel(module string, l zerolog.Level, source string) {
+	levelsMu.Lock()
+	from, ok := moduleLevels[module]
+	moduleLevels[module] = l
+	levelsMu.Unlock()
+
+	if !ok {
+		from = GetLevel()
+	}
+	if from != l {
+		logChange(module, from, l, source)
+	}
+}
+
+func clearModuleLevel(module string, source string) {
+	levelsMu.Lock()
+	from, ok := moduleLevels[module]
+	delete(moduleLevels, module)
+	levelsMu.Unlock()
+
+	if to := GetLevel(); ok && from != to {
+		logChange(module, from, to, source)
+	}
+}
+
+// applyConfiguredLevels records the levels read from config and applies them
+func applyConfiguredLevels(global zerolog.Level, modules map[string]zerolog.Level) {
+	configured.Store(int32(global))
+	levelsMu.Lock()
+	configuredModules = modules
+	if !hasConfig.Swap(true) {
+		// 首次加载配置时直接生效, 不记录变更
+		level.Store(int32(global))
+		for module, l := range modules {
+			moduleLevels[module] = l
+		}
+		levelsMu.Unlock()
+		return
+	}
+	levelsMu.Unlock()
+
+	applyLevels(global, modules, "config")
+}
+
+func resetLevels(source string) {
+	levelsMu.RLock()
+	modules := configuredModules
+	levelsMu.RUnlock()
+
+	applyLevels(zerolog.Level(configured.Load()), modules, source)
+}
+
+// applyLevels replaces the global and module levels
+func applyLevels(global zerolog.Level, modules map[string]zerolog.Level, source string) {
+	setLevel(global, source)
+
+	for module := range ModuleLevels() {
+		if _, ok := modules[module]; !ok {
+			clearModuleLevel(module, source)
+		}
+	}
+	for module, l := range modules {
+		setModuleLevel(module, l, source)
+	}
+}
+
+// logChange records a level change regardless of the current level
+func logChange(module string, from, to zerolog.Level, source string) {
+	l := Logger()
+	e := l.Log().Str("from", from.String()).Str("to", to.String()).Str("source", source)
+	if module != "" {
+		e = e.Str(ModuleFieldName, module)
+	}
+	e.Msg("log level changed")
+}
@@ -0,0 +1,40 @@
+package logger_test
+
+import (
+	"testing"
+
+	"github.com/Dankko0w0/gospike/logger"
+	"github.com/Dankko0w0/gospike/logger/loggertest"
+	"github.com/rs/zerolog"
+)
+
+func TestModuleLevel(t *testing.T) {
+	rec := loggertest.New(t)
+	logger.SetModuleLevel("db", zerolog.InfoLevel)
+
+	l := logger.Module("db")
+	l.Debug().Msg("hidden")
+	l.Info().Msg("shown")
+	other := logger.Module("http")
+	other.Debug().Msg("other debug")
+
+	rec.AssertNotLogged(t, loggertest.Message("hidden"))
+	rec.AssertLogged(t, loggertest.Message("shown"), loggertest.Field(logger.ModuleFieldName, "db"))
+	rec.AssertLogged(t, loggertest.Message("other debug"), loggertest.Level(zerolog.DebugLevel))
+
+	logger.ClearModuleLevel("db")
+	l.Debug().Msg("visible again")
+	rec.AssertLogged(t, loggertest.Message("visible again"))
+}
+
+func TestDisabledEventsAreNotBuilt(t *testing.T) {
+	loggertest.New(t)
+	logger.SetLevel(zerolog.WarnLevel)
+
+	built := false
+	l := logger.Module("db")
+	l.Debug().Func(func(*zerolog.Event) { built = true }).Msg("hidden")
+	if built {
+		t.Fatal("debug event was built while the level is warn")
+	}
+}
@@ -1,76 +1,143 @@
 package logger
 
 import (
+	"context"
 	"io"
-	"os"
 	"sync"
 
 	"github.com/Dankko0w0/gospike/models"
 	"github.com/rs/zerolog"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var (
-	logger zerolog.Logger
+	output = &switchWriter{}
+	root   = newLogger(output)
+	logger = filteredLogger("")
 	once   sync.Once
 )
 
 // InitializeLogger initializes the logger with specified settings
 func InitializeLogger(logToConsole bool, logToFile bool, logFilePath string, maxFileSize int, maxBackups int, maxAge int, compress bool, consoleFormat *models.ConsoleFormat) {
 	once.Do(func() {
-		var writers []io.Writer
-
-		if logToConsole {
-			consoleWriter := zerolog.ConsoleWriter{Out: os.Stdout}
-
-			// 自定义控制台打印格式
-			if consoleFormat == nil {
-				defaultConsoleFormat := models.DefaultConsoleFormat()
-				consoleFormat = &defaultConsoleFormat
-			}
-			consoleWriter.TimeFormat = consoleFormat.TimeFormat
-			consoleWriter.NoColor = consoleFormat.NoColor
-			if len(consoleFormat.PartsOrder) > 0 {
-				consoleWriter.PartsOrder = consoleFormat.PartsOrder
-			}
-			if len(consoleFormat.PartsExclude) > 0 {
-				consoleWriter.PartsExclude = consoleFormat.PartsExclude
-			}
-
-			writers = append(writers, consoleWriter)
-		}
-
-		if logToFile {
-			writers = append(writers, &lumberjack.Logger{
-				Filename:   logFilePath,
-				MaxSize:    maxFileSize, // megabytes
-				MaxBackups: maxBackups,
-				MaxAge:     maxAge, // days
-				Compress:   compress,
-			})
-		}
-
-		multi := io.MultiWriter(writers...)
-		logger = zerolog.New(multi).With().Timestamp().Logger()
+		_ = InitFromConfig(models.LoggerConfig{
+			Level:         zerolog.LevelTraceValue,
+			LogToConsole:  logToConsole,
+			ConsoleFormat: consoleFormat,
+			LogToFile:     logToFile,
+			LogFilePath:   logFilePath,
+			MaxFileSize:   maxFileSize,
+			MaxBackups:    maxBackups,
+			MaxAge:        maxAge,
+			Compress:      compress,
+		})
 	})
 }
 
+// Logger returns a copy of the current logger
+func Logger() zerolog.Logger {
+	return logger
+}
+
+// Flush waits until buffered events have been written
+func Flush(ctx context.Context) error {
+	return output.flush(ctx)
+}
+
+// Close flushes buffered events and closes all outputs. Events logged
+// afterwards are discarded until the logger is configured again.
+func Close(ctx context.Context) error {
+	err := Flush(ctx)
+	output.swap(nil, nil)
+	return err
+}
+
+// newLogger creates an unfiltered, unsampled logger; levels and sampling
+// are applied by the levelSampler attached to the loggers derived from it
+func newLogger(w io.Writer) zerolog.Logger {
+	return zerolog.New(w).Level(zerolog.TraceLevel).With().Timestamp().Logger()
+}
+
 // Info logs an info message
 func Info(msg string) {
-	logger.Info().Msg(msg)
+	l := Logger()
+	l.Info().Msg(msg)
 }
 
 // Error logs an error message
 func Error(msg string, err error) {
-	logger.Error().Err(err).Msg(msg)
+	l := Logger()
+	l.Error().Err(err).Msg(msg)
 }
 
 // Debug logs a debug message
 func Debug(msg string) {
-	logger.Debug().Msg(msg)
+	l := Logger()
+	l.Debug().Msg(msg)
 }
 
 // Warn logs a warning message
 func Warn(msg string) {
-	logger.Warn().Msg(msg)
+	l := Logger()
+	l.Warn().Msg(msg)
+}
+
+// switchWriter forwards to a writer that can be replaced at runtime, so
+// loggers derived before a reload keep writing to the current outputs.
+type switchWriter struct {
+	mu      sync.RWMutex
+	w       zerolog.LevelWriter
+	closers []io.Closer
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	return s.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (s *switchWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.w == nil {
+		return len(p), nil
+	}
+	return s.w.WriteLevel(level, p)
+}
+
+func (s *switchWriter) flush(ctx context.Context) error {
+	s.mu.RLock()
+	w := s.w
+	s.mu.RUnlock()
+
+	if f, ok := w.(interface{ Flush(context.Context) error }); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// outputs returns the closers of the current configuration
+func (s *switchWriter) outputs() []io.Closer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]io.Closer(nil), s.closers...)
+}
+
+// take installs w and returns the previous writer and closers without
+// closing them
+func (s *switchWriter) take(w zerolog.LevelWriter, closers []io.Closer) (zerolog.LevelWriter, []io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, oldClosers := s.w, s.closers
+	s.w, s.closers = w, closers
+	return old, oldClosers
+}
+
+// swap installs w and closes the writers of the previous configuration
+func (s *switchWriter) swap(w zerolog.LevelWriter, closers []io.Closer) {
+	s.mu.Lock()
+	old := s.closers
+	s.w, s.closers = w, closers
+	s.mu.Unlock()
+
+	for _, c := range old {
+		c.Close()
+	}
 }
@@ -0,0 +1,209 @@
+// Package loggertest provides an in-memory sink and matchers for asserting
+// on the output of the logger package in tests.
+package loggertest
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/logger"
+	"github.com/rs/zerolog"
+)
+
+// Entry is a decoded log event
+type Entry struct {
+	Level   zerolog.Level
+	Message string
+	Fields  map[string]interface{}
+	Raw     string
+}
+
+// Recorder is an in-memory log sink
+type Recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New replaces the global logger for the duration of the test with a
+// Recorder, also echoing events to t
+func New(t testing.TB) *Recorder {
+	t.Helper()
+	r := NewRecorder()
+	logger.ReplaceForTest(t, r, logger.TestWriter(t))
+	return r
+}
+
+// NewRecorder returns an empty Recorder that can be used as any writer
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	return r.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (r *Recorder) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return 0, err
+	}
+
+	if name, ok := fields[zerolog.LevelFieldName].(string); ok && level == zerolog.NoLevel {
+		if parsed, err := zerolog.ParseLevel(name); err == nil {
+			level = parsed
+		}
+	}
+	msg, _ := fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.MessageFieldName)
+
+	r.mu.Lock()
+	r.entries = append(r.entries, Entry{
+		Level:   level,
+		Message: msg,
+		Fields:  fields,
+		Raw:     strings.TrimSpace(string(p)),
+	})
+	r.mu.Unlock()
+	return len(p), nil
+}
+
+// Entries returns the recorded events
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Reset discards the recorded events
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// Filter returns the events matching all matchers
+func (r *Recorder) Filter(matchers ...Matcher) []Entry {
+	var matched []Entry
+	for _, e := range r.Entries() {
+		if e.Match(matchers...) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// AssertLogged fails the test unless an event matches all matchers
+func (r *Recorder) AssertLogged(t testing.TB, matchers ...Matcher) Entry {
+	t.Helper()
+	matched := r.Filter(matchers...)
+	if len(matched) == 0 {
+		t.Errorf("no log entry matches %s; got:\n%s", describe(matchers), r.dump())
+		return Entry{}
+	}
+	return matched[0]
+}
+
+// AssertNotLogged fails the test if an event matches all matchers
+func (r *Recorder) AssertNotLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+	if matched := r.Filter(matchers...); len(matched) > 0 {
+		t.Errorf("unexpected log entry matching %s: %s", describe(matchers), matched[0].Raw)
+	}
+}
+
+func (r *Recorder) dump() string {
+	var b strings.Builder
+	for _, e := range r.Entries() {
+		b.WriteString("  ")
+		b.WriteString(e.Raw)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Match reports whether e matches all matchers
+func (e Entry) Match(matchers ...Matcher) bool {
+	for _, m := range matchers {
+		if !m.Match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matcher selects log events
+type Matcher struct {
+	Description string
+	Match       func(Entry) bool
+}
+
+// Level matches events at level
+func Level(level zerolog.Level) Matcher {
+	return Matcher{"level=" + level.String(), func(e Entry) bool {
+		return e.Level == level
+	}}
+}
+
+// Message matches events whose message equals msg
+func Message(msg string) Matcher {
+	return Matcher{"message=" + msg, func(e Entry) bool {
+		return e.Message == msg
+	}}
+}
+
+// MessageContains matches events whose message contains substr
+func MessageContains(substr string) Matcher {
+	return Matcher{"message~" + substr, func(e Entry) bool {
+		return strings.Contains(e.Message, substr)
+	}}
+}
+
+// HasField matches events carrying the field key
+func HasField(key string) Matcher {
+	return Matcher{"has " + key, func(e Entry) bool {
+		_, ok := e.Fields[key]
+		return ok
+	}}
+}
+
+// Field matches events whose field key equals value once both are
+// encoded as JSON, so Field("n", 3) matches a decoded float64
+func Field(key string, value interface{}) Matcher {
+	want := normalize(value)
+	return Matcher{key + "=" + jsonString(value), func(e Entry) bool {
+		got, ok := e.Fields[key]
+		return ok && reflect.DeepEqual(got, want)
+	}}
+}
+
+func normalize(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func jsonString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "?"
+	}
+	return string(b)
+}
+
+func describe(matchers []Matcher) string {
+	parts := make([]string, len(matchers))
+	for i, m := range matchers {
+		parts[i] = m.Description
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
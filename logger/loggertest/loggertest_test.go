@@ -0,0 +1,172 @@
+package loggertest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/logger"
+	"github.com/rs/zerolog"
+)
+
+// fakeTB records the failures reported by the assertions
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorderConcurrentWrites(t *testing.T) {
+	rec := NewRecorder()
+	l := zerolog.New(rec)
+
+	const goroutines, events = 8, 100
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < events; i++ {
+				l.Info().Int("g", g).Int("i", i).Msg("event")
+				// 读取与写入并发进行
+				rec.Filter(Field("g", g))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if got := len(rec.Entries()); got != goroutines*events {
+		t.Fatalf("recorded %d entries, want %d", got, goroutines*events)
+	}
+	for g := 0; g < goroutines; g++ {
+		if got := len(rec.Filter(Field("g", g))); got != events {
+			t.Errorf("goroutine %d recorded %d entries, want %d", g, got, events)
+		}
+	}
+
+	rec.Reset()
+	if got := len(rec.Entries()); got != 0 {
+		t.Fatalf("Reset() left %d entries", got)
+	}
+}
+
+func TestRecorderDecodesEvents(t *testing.T) {
+	rec := NewRecorder()
+	l := zerolog.New(rec)
+	l.Warn().Str("user", "bob").Int("n", 3).Msg("hello")
+
+	entries := rec.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("recorded %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Level != zerolog.WarnLevel || e.Message != "hello" {
+		t.Fatalf("entry = %+v, want a warn entry with message hello", e)
+	}
+	if _, ok := e.Fields[zerolog.LevelFieldName]; ok {
+		t.Errorf("level left in fields: %v", e.Fields)
+	}
+	if _, ok := e.Fields[zerolog.MessageFieldName]; ok {
+		t.Errorf("message left in fields: %v", e.Fields)
+	}
+	if e.Raw != `{"level":"warn","user":"bob","n":3,"message":"hello"}` {
+		t.Errorf("Raw = %s", e.Raw)
+	}
+
+	if _, err := rec.Write([]byte("not json")); err == nil {
+		t.Fatal("Write() accepted invalid JSON")
+	}
+}
+
+func TestNewReplacesGlobalLogger(t *testing.T) {
+	rec := New(t)
+	logger.Info("through the global logger")
+	rec.AssertLogged(t, Message("through the global logger"), Level(zerolog.InfoLevel))
+}
+
+func TestMatchers(t *testing.T) {
+	rec := NewRecorder()
+	l := zerolog.New(rec)
+	l.Error().Str("user", "bob").Int("n", 3).Strs("tags", []string{"a", "b"}).Msg("request failed")
+
+	tests := []struct {
+		matcher     Matcher
+		description string
+		match       bool
+	}{
+		{Level(zerolog.ErrorLevel), "level=error", true},
+		{Level(zerolog.InfoLevel), "level=info", false},
+		{Message("request failed"), "message=request failed", true},
+		{Message("request"), "message=request", false},
+		{MessageContains("fail"), "message~fail", true},
+		{MessageContains("ok"), "message~ok", false},
+		{HasField("user"), "has user", true},
+		{HasField("missing"), "has missing", false},
+		{Field("user", "bob"), `user="bob"`, true},
+		{Field("user", "alice"), `user="alice"`, false},
+		{Field("n", 3), "n=3", true},
+		{Field("n", "3"), `n="3"`, false},
+		{Field("tags", []string{"a", "b"}), `tags=["a","b"]`, true},
+		{Field("missing", nil), "missing=null", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if tt.matcher.Description != tt.description {
+				t.Errorf("Description = %q, want %q", tt.matcher.Description, tt.description)
+			}
+
+			tb := &fakeTB{TB: t}
+			rec.AssertLogged(tb, tt.matcher)
+			if tt.match && len(tb.errors) > 0 {
+				t.Errorf("AssertLogged() failed: %s", tb.errors)
+			}
+			if !tt.match {
+				want := "no log entry matches [" + tt.description + "]; got:\n  " + rec.Entries()[0].Raw + "\n"
+				if len(tb.errors) != 1 || tb.errors[0] != want {
+					t.Errorf("AssertLogged() errors = %q, want %q", tb.errors, want)
+				}
+			}
+
+			tb = &fakeTB{TB: t}
+			rec.AssertNotLogged(tb, tt.matcher)
+			if !tt.match && len(tb.errors) > 0 {
+				t.Errorf("AssertNotLogged() failed: %s", tb.errors)
+			}
+			if tt.match {
+				want := "unexpected log entry matching [" + tt.description + "]: " + rec.Entries()[0].Raw
+				if len(tb.errors) != 1 || tb.errors[0] != want {
+					t.Errorf("AssertNotLogged() errors = %q, want %q", tb.errors, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssertLoggedCombinesMatchers(t *testing.T) {
+	rec := NewRecorder()
+	l := zerolog.New(rec)
+	l.Info().Str("user", "bob").Msg("first")
+	l.Info().Str("user", "alice").Msg("second")
+
+	tb := &fakeTB{TB: t}
+	e := rec.AssertLogged(tb, HasField("user"), Field("user", "alice"))
+	if len(tb.errors) > 0 || e.Message != "second" {
+		t.Fatalf("AssertLogged() = %+v, errors %q", e, tb.errors)
+	}
+
+	tb = &fakeTB{TB: t}
+	e = rec.AssertLogged(tb, Message("first"), Field("user", "alice"))
+	if len(tb.errors) != 1 || e.Message != "" {
+		t.Fatalf("AssertLogged() = %+v, errors %q", e, tb.errors)
+	}
+	if !strings.Contains(tb.errors[0], `[message=first, user="alice"]`) ||
+		strings.Count(tb.errors[0], "\n  ") != 2 {
+		t.Errorf("failure output does not describe matchers and dump entries: %s", tb.errors[0])
+	}
+}
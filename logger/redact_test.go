@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Dankko0w0/gospike/models"
+)
+
+func TestRedactorFromConfigKeepsDefaults(t *testing.T) {
+	r, err := newRedactorFromConfig(models.RedactConfig{Keys: []string{"ssn"}, Patterns: []string{"email"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := `{"password":"p","ssn":"123","message":"postgres://u:secret@db:5432/app by a@b.io with Bearer abc"}`
+	out := string(r.Redact([]byte(in)))
+	for _, leaked := range []string{`"p"`, "123", "secret", "a@b.io", "abc"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("%q was not redacted: %s", leaked, out)
+		}
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	r, err := NewRedactor(nil, []string{"dsn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"postgres://user:pw@db:5432/app":            "postgres://user:" + Redacted + "@db:5432/app",
+		"redis://:pw@cache:6379/0":                  "redis://:" + Redacted + "@cache:6379/0",
+		"http://example.com:8080/path":              "http://example.com:8080/path",
+		"http://example.com:8080/users/a@b.io":      "http://example.com:8080/users/a@b.io",
+		"see https://api.example.com:443/v1@latest": "see https://api.example.com:443/v1@latest",
+	}
+	for in, want := range tests {
+		if got := r.RedactString(in); got != want {
+			t.Errorf("RedactString(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+	"github.com/Dankko0w0/gospike/utils"
+)
+
+// Rotation intervals
+const (
+	RotateDaily  = "daily"
+	RotateHourly = "hourly"
+)
+
+const defaultMaxFileSize = 100 // megabytes
+
+// PostRotateHook is called with the path of every rotated file, after it
+// has been compressed
+type PostRotateHook func(path string) error
+
+var postRotateHook atomic.Pointer[PostRotateHook]
+
+// SetPostRotateHook sets the hook run after a log file is rotated; nil
+// removes it
+func SetPostRotateHook(hook PostRotateHook) {
+	if hook == nil {
+		postRotateHook.Store(nil)
+		return
+	}
+	postRotateHook.Store(&hook)
+}
+
+// UploadToSMB returns a PostRotateHook that uploads rotated files to the
+// root of the SMB share named share, e.g. "SharedFolder". Subdirectories
+// are not supported since the share name is mounted as is.
+func UploadToSMB(client *utils.SMBClient, share string) PostRotateHook {
+	return func(path string) error {
+		if share == "" || strings.ContainsAny(share, `/\`) {
+			return fmt.Errorf("invalid SMB share name %q", share)
+		}
+		return client.UploadFile(path, share+"/"+filepath.Base(path))
+	}
+}
+
+// rotateWriter writes to a file named after the current period and
+// rotates it when the period ends or the file grows past maxSize. The
+// configured path is kept as a symlink to the active file.
+type rotateWriter struct {
+	link       string
+	dir        string
+	prefix     string // <name>-
+	ext        string
+	interval   time.Duration
+	timeFormat string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	maxTotal   int64
+	compress   bool
+
+	mu        sync.Mutex
+	file      *os.File
+	path      string
+	active    atomic.Value // base name of path, read by postRotate
+	size      int64
+	periodEnd time.Time
+
+	// rotated queues files for postRotate; skipped counts rotated files
+	// not queued because postRotate fell behind
+	rotated chan string
+	skipped atomic.Uint64
+	done    chan struct{}
+}
+
+func newRotateWriter(cfg models.LoggerConfig) (*rotateWriter, error) {
+	w := &rotateWriter{
+		link:       cfg.LogFilePath,
+		dir:        filepath.Dir(cfg.LogFilePath),
+		ext:        filepath.Ext(cfg.LogFilePath),
+		timeFormat: cfg.Rotation.TimeFormat,
+		maxSize:    int64(cfg.MaxFileSize) * 1024 * 1024,
+		maxBackups: cfg.MaxBackups,
+		maxAge:     time.Duration(cfg.MaxAge) * 24 * time.Hour,
+		maxTotal:   int64(cfg.Rotation.MaxTotalSize) * 1024 * 1024,
+		compress:   cfg.Compress,
+		rotated:    make(chan string, 16),
+		done:       make(chan struct{}),
+	}
+	w.prefix = strings.TrimSuffix(filepath.Base(cfg.LogFilePath), w.ext) + "-"
+	if w.maxSize <= 0 {
+		w.maxSize = defaultMaxFileSize * 1024 * 1024
+	}
+
+	switch strings.ToLower(cfg.Rotation.Interval) {
+	case RotateDaily:
+		w.interval = 24 * time.Hour
+		if w.timeFormat == "" {
+			w.timeFormat = "2006-01-02"
+		}
+	case RotateHourly:
+		w.interval = time.Hour
+		if w.timeFormat == "" {
+			w.timeFormat = "2006-01-02T15"
+		}
+	case "":
+		if w.timeFormat == "" {
+			w.timeFormat = "2006-01-02T15-04-05"
+		}
+	default:
+		return nil, fmt.Errorf("invalid rotation interval %q", cfg.Rotation.Interval)
+	}
+
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	go w.postRotate()
+	return w, nil
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	if w.file == nil {
+		if err := w.open(now); err != nil {
+			return 0, err
+		}
+	} else if (w.interval > 0 && !now.Before(w.periodEnd)) || w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// open opens the file for the period containing now. On the first open an
+// existing file with room left is appended to; after a rotation a new file
+// is always started.
+func (w *rotateWriter) open(now time.Time) error {
+	reuse := w.path == ""
+	start := now
+	if w.interval > 0 {
+		start = truncate(now, w.interval)
+		w.periodEnd = start.Add(w.interval)
+	}
+
+	base := filepath.Join(w.dir, w.prefix+start.Format(w.timeFormat))
+	path := base + w.ext
+	for i := 1; w.taken(path, reuse); i++ {
+		path = base + "." + strconv.Itoa(i) + w.ext
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	w.file, w.path, w.size = file, path, info.Size()
+	w.active.Store(filepath.Base(path))
+	w.updateLink()
+	return nil
+}
+
+// taken reports whether path cannot be written to: it has been rotated
+// already, or it exists and reuse is not allowed or it is full
+func (w *rotateWriter) taken(path string, reuse bool) bool {
+	if _, err := os.Stat(path + ".gz"); err == nil {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && (!reuse || info.Size() >= w.maxSize)
+}
+
+func (w *rotateWriter) rotate(now time.Time) error {
+	old := w.path
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	w.file = nil
+
+	if err := w.open(now); err != nil {
+		return err
+	}
+	if w.rotated != nil {
+		// 不能阻塞: 写入方持有 w.mu, postRotate 落后时跳过该文件的后处理
+		select {
+		case w.rotated <- old:
+		default:
+			w.skipped.Add(1)
+			fmt.Fprintf(os.Stderr, "logger: post-rotate queue is full, skipped %s\n", old)
+		}
+	}
+	return nil
+}
+
+// updateLink points the configured path at the active file. Failures are
+// ignored since symlinks are not available everywhere.
+func (w *rotateWriter) updateLink() {
+	tmp := w.link + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(filepath.Base(w.path), tmp); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, w.link); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// Close closes the active file and waits for the post-rotate work queued
+// before it, without holding the lock so postRotate can still log
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	if w.rotated == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	close(w.rotated)
+	w.rotated = nil
+
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return err
+}
+
+// postRotate compresses rotated files, runs the hook and applies the
+// retention policy in the background. Failures go to stderr rather than
+// the logger, which may be writing to this same file.
+func (w *rotateWriter) postRotate() {
+	defer close(w.done)
+
+	for path := range w.rotated {
+		if w.compress {
+			compressed, err := compressFile(path)
+			if err != nil {
+				rotateError("failed to compress rotated log file", err)
+			} else {
+				path = compressed
+			}
+		}
+		if hook := postRotateHook.Load(); hook != nil {
+			if err := (*hook)(path); err != nil {
+				rotateError("post-rotate hook failed", err)
+			}
+		}
+		if err := w.removeExpired(); err != nil {
+			rotateError("failed to remove old log files", err)
+		}
+	}
+}
+
+func rotateError(msg string, err error) {
+	fmt.Fprintf(os.Stderr, "logger: %s: %v\n", msg, err)
+}
+
+// removeExpired deletes backups beyond the count, age and total size limits
+func (w *rotateWriter) removeExpired() error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+
+	active, _ := w.active.Load().(string)
+
+	type backup struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || name == active || !strings.HasPrefix(name, w.prefix) ||
+			!(strings.HasSuffix(name, w.ext) || strings.HasSuffix(name, w.ext+".gz")) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{filepath.Join(w.dir, name), info.Size(), info.ModTime()})
+	}
+
+	// 从新到旧排列
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	var total int64
+	for i, b := range backups {
+		total += b.size
+		expired := (w.maxBackups > 0 && i >= w.maxBackups) ||
+			(w.maxAge > 0 && time.Since(b.modTime) > w.maxAge) ||
+			(w.maxTotal > 0 && total > w.maxTotal)
+		if expired {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// truncate returns the start of the period containing t in local time
+func truncate(t time.Time, interval time.Duration) time.Time {
+	if interval == 24*time.Hour {
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// compressFile gzips path and removes the original
+func compressFile(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		return "", err
+	}
+
+	src.Close()
+	return path + ".gz", os.Remove(path)
+}
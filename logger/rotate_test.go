@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+)
+
+func TestRotateWriterDoesNotBlockOnSlowHook(t *testing.T) {
+	w, err := newRotateWriter(models.LoggerConfig{
+		LogFilePath: filepath.Join(t.TempDir(), "app.log"),
+		Rotation:    models.RotationConfig{TimeFormat: "20060102T150405.000000000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.maxSize = 8
+
+	release := make(chan struct{})
+	SetPostRotateHook(func(string) error {
+		<-release
+		// 钩子写回同一个 writer, 与日志记录钩子失败的情形相同
+		_, err := w.Write([]byte("from hook\n"))
+		return err
+	})
+	defer SetPostRotateHook(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			w.Write([]byte("0123456789\n"))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write blocked while the post-rotate hook was slow")
+	}
+	if w.skipped.Load() == 0 {
+		t.Error("no rotated file was skipped although the queue was full")
+	}
+
+	closed := make(chan error)
+	go func() { closed <- w.Close() }()
+	close(release)
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close deadlocked with the post-rotate hook")
+	}
+}
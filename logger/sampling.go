@@ -0,0 +1,196 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+	"github.com/rs/zerolog"
+)
+
+const defaultDedupWindow = 10 * time.Second
+
+var (
+	sampling dynamicSampler
+	dedup    atomic.Pointer[deduper]
+)
+
+// dynamicSampler delegates to the sampler of the current configuration so
+// loggers derived earlier follow configuration changes
+type dynamicSampler struct {
+	current atomic.Pointer[zerolog.LevelSampler]
+}
+
+func (s *dynamicSampler) Sample(level zerolog.Level) bool {
+	ls := s.current.Load()
+	if ls == nil {
+		return true
+	}
+	return ls.Sample(level)
+}
+
+// newLevelSampler builds a sampler from the per-level configuration, or
+// returns nil when sampling is not configured
+func newLevelSampler(cfg map[string]models.SamplingConfig) (*zerolog.LevelSampler, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+
+	ls := &zerolog.LevelSampler{}
+	for name, c := range cfg {
+		level, err := zerolog.ParseLevel(strings.ToLower(name))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sampling level %q: %w", name, err)
+		}
+
+		var s zerolog.Sampler
+		if c.Every > 0 {
+			s = &zerolog.BasicSampler{N: c.Every}
+		}
+		if c.Burst > 0 {
+			if c.Period <= 0 {
+				return nil, fmt.Errorf("sampling for level %q sets burst without a period", name)
+			}
+			// 周期内超过 Burst 的部分交给 BasicSampler, Every 为 0 时全部丢弃
+			s = &zerolog.BurstSampler{Burst: c.Burst, Period: c.Period, NextSampler: s}
+		}
+
+		switch level {
+		case zerolog.TraceLevel:
+			ls.TraceSampler = s
+		case zerolog.DebugLevel:
+			ls.DebugSampler = s
+		case zerolog.InfoLevel:
+			ls.InfoSampler = s
+		case zerolog.WarnLevel:
+			ls.WarnSampler = s
+		case zerolog.ErrorLevel:
+			ls.ErrorSampler = s
+		default:
+			return nil, fmt.Errorf("sampling is not supported for level %q", name)
+		}
+	}
+	return ls, nil
+}
+
+func applySampling(ls *zerolog.LevelSampler, cfg models.DedupConfig) {
+	sampling.current.Store(ls)
+
+	var d *deduper
+	if cfg.Enabled {
+		d = newDeduper(cfg.Window)
+	}
+	if old := dedup.Swap(d); old != nil {
+		old.stop()
+	}
+}
+
+type dedupKey struct {
+	module string
+	level  zerolog.Level
+	msg    string
+}
+
+type dedupEntry struct {
+	expires  time.Time
+	repeated int
+}
+
+// deduper suppresses identical messages within a window and reports how
+// many were suppressed once the window has passed
+type deduper struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[dedupKey]*dedupEntry
+	done chan struct{}
+}
+
+func newDeduper(window time.Duration) *deduper {
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	d := &deduper{
+		window: window,
+		seen:   map[dedupKey]*dedupEntry{},
+		done:   make(chan struct{}),
+	}
+	go d.sweep()
+	return d
+}
+
+// allow reports whether the message should be written
+func (d *deduper) allow(module string, level zerolog.Level, msg string) bool {
+	key := dedupKey{module: module, level: level, msg: msg}
+	now := time.Now()
+
+	d.mu.Lock()
+	e, ok := d.seen[key]
+	if ok && now.Before(e.expires) {
+		e.repeated++
+		d.mu.Unlock()
+		return false
+	}
+	d.seen[key] = &dedupEntry{expires: now.Add(d.window)}
+	d.mu.Unlock()
+
+	if ok && e.repeated > 0 {
+		summarize(key, e.repeated)
+	}
+	return true
+}
+
+// sweep reports and forgets expired messages
+func (d *deduper) sweep() {
+	ticker := time.NewTicker(d.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			d.flush(time.Time{})
+			return
+		case now := <-ticker.C:
+			d.flush(now)
+		}
+	}
+}
+
+// flush reports the messages that expired before now, or all of them when
+// now is zero
+func (d *deduper) flush(now time.Time) {
+	repeated := map[dedupKey]int{}
+
+	d.mu.Lock()
+	for key, e := range d.seen {
+		if now.IsZero() || !now.Before(e.expires) {
+			if e.repeated > 0 {
+				repeated[key] = e.repeated
+			}
+			delete(d.seen, key)
+		}
+	}
+	d.mu.Unlock()
+
+	for key, n := range repeated {
+		summarize(key, n)
+	}
+}
+
+func (d *deduper) stop() {
+	close(d.done)
+}
+
+// summarize logs how many times a message was suppressed. It writes
+// through root, which has no hooks or sampler, so the summary is neither
+// deduplicated nor sampled.
+func summarize(key dedupKey, n int) {
+	e := root.WithLevel(key.level).Int("repeated", n)
+	if key.module != "" {
+		e = e.Str(ModuleFieldName, key.module)
+	}
+	e.Msgf("%s (repeated %d times)", key.msg, n)
+}
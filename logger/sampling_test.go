@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+	"github.com/rs/zerolog"
+)
+
+func TestNewLevelSamplerRequiresPeriod(t *testing.T) {
+	if _, err := newLevelSampler(map[string]models.SamplingConfig{"info": {Burst: 5}}); err == nil {
+		t.Fatal("burst without period was accepted")
+	}
+	if _, err := newLevelSampler(map[string]models.SamplingConfig{"info": {Burst: 5, Period: time.Second}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDedupSummaryIsNotSampled(t *testing.T) {
+	var out countWriter
+	ReplaceForTest(t, &out)
+
+	// 丢弃所有 info 事件, 汇总仍然要写出
+	sampling.current.Store(&zerolog.LevelSampler{InfoSampler: zerolog.RandomSampler(0)})
+	summarize(dedupKey{level: zerolog.InfoLevel, msg: "repeated"}, 3)
+	if out.n != 1 {
+		t.Fatalf("summary written %d times, want 1", out.n)
+	}
+}
+
+type countWriter struct{ n int }
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n++
+	return len(p), nil
+}
@@ -0,0 +1,210 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+)
+
+const (
+	defaultShipperBuffer = 1024
+	shipperMinBackoff    = 100 * time.Millisecond
+	shipperMaxBackoff    = 30 * time.Second
+	shipperCloseTimeout  = 5 * time.Second
+)
+
+// Shipper sends newline-delimited JSON events to a remote collector from a
+// background goroutine. Events are queued in a bounded buffer; when it is
+// full the oldest event is dropped. It is the "tcp-json" sink.
+type Shipper struct {
+	network string
+	address string
+	size    int
+
+	mu      sync.Mutex
+	queue   [][]byte
+	conn    net.Conn
+	dropped atomic.Uint64
+
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newShipperSink(cfg models.SinkConfig) (io.Writer, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	if _, err := sinkOptions(cfg); err != nil {
+		return nil, err
+	}
+	return NewShipper(cfg.Network, cfg.Address, cfg.BufferSize), nil
+}
+
+// NewShipper starts a Shipper that sends events to address, buffering up
+// to bufferSize events. network defaults to "tcp" and bufferSize to 1024.
+func NewShipper(network, address string, bufferSize int) *Shipper {
+	if network == "" {
+		network = "tcp"
+	}
+	if bufferSize <= 0 {
+		bufferSize = defaultShipperBuffer
+	}
+
+	s := &Shipper{
+		network: network,
+		address: address,
+		size:    bufferSize,
+		notify:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// ShipperDropped returns the number of events dropped by the shippers of
+// the current configuration
+func ShipperDropped() uint64 {
+	var n uint64
+	for _, c := range output.outputs() {
+		if s, ok := c.(*Shipper); ok {
+			n += s.Dropped()
+		}
+	}
+	return n
+}
+
+func (s *Shipper) Write(p []byte) (int, error) {
+	msg := make([]byte, len(p), len(p)+1)
+	copy(msg, p)
+	if !bytes.HasSuffix(msg, []byte("\n")) {
+		msg = append(msg, '\n')
+	}
+
+	s.mu.Lock()
+	s.push(msg)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of events dropped because the buffer was full
+func (s *Shipper) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close sends the buffered events, waiting up to five seconds, and closes
+// the connection
+func (s *Shipper) Close() error {
+	s.once.Do(func() { close(s.stop) })
+
+	select {
+	case <-s.done:
+	case <-time.After(shipperCloseTimeout):
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// push appends msgs to the queue, dropping the oldest events on overflow.
+// The caller must hold s.mu.
+func (s *Shipper) push(msgs ...[]byte) {
+	s.queue = append(s.queue, msgs...)
+	if over := len(s.queue) - s.size; over > 0 {
+		s.dropped.Add(uint64(over))
+		s.queue = append(s.queue[:0], s.queue[over:]...)
+	}
+}
+
+func (s *Shipper) take() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.queue
+	s.queue = nil
+	return batch
+}
+
+// requeue puts a failed batch back in front of newer events
+func (s *Shipper) requeue(batch [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	newer := s.queue
+	s.queue = nil
+	s.push(batch...)
+	s.push(newer...)
+}
+
+func (s *Shipper) run() {
+	defer close(s.done)
+
+	backoff := shipperMinBackoff
+	for {
+		batch := s.take()
+		if len(batch) == 0 {
+			select {
+			case <-s.notify:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+
+		if err := s.send(batch); err != nil {
+			s.requeue(batch)
+			select {
+			case <-time.After(backoff):
+			case <-s.stop:
+				return
+			}
+			backoff = min(backoff*2, shipperMaxBackoff)
+			continue
+		}
+		backoff = shipperMinBackoff
+	}
+}
+
+// send writes a batch, dialing the collector if needed
+func (s *Shipper) send(batch [][]byte) error {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+
+	if conn == nil {
+		var err error
+		if conn, err = net.DialTimeout(s.network, s.address, 5*time.Second); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.conn = conn
+		s.mu.Unlock()
+	}
+
+	buffers := net.Buffers(append([][]byte{}, batch...))
+	if _, err := buffers.WriteTo(conn); err != nil {
+		conn.Close()
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
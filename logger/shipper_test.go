@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+)
+
+func TestShipperSendsToCollector(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s := NewShipper("tcp", ln.Addr().String(), 0)
+	defer s.Close()
+	s.Write([]byte(`{"message":"one"}`))
+	s.Write([]byte(`{"message":"two"}` + "\n"))
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewScanner(conn)
+	for _, want := range []string{"one", "two"} {
+		if !r.Scan() {
+			t.Fatalf("collector stopped reading: %v", r.Err())
+		}
+		var event struct{ Message string }
+		if err := json.Unmarshal(r.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", r.Text(), err)
+		}
+		if event.Message != want {
+			t.Errorf("got message %q, want %q", event.Message, want)
+		}
+	}
+}
+
+func TestShipperDropsOldestWhenFull(t *testing.T) {
+	// 监听后立即关闭, 保证连接被拒绝
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	err = InitFromConfig(models.LoggerConfig{
+		Sinks: []models.SinkConfig{{Type: "tcp-json", Address: addr, BufferSize: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(context.Background())
+
+	for i := 0; i < 5; i++ {
+		Info("event")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for ShipperDropped() != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("ShipperDropped() = %d, want 3", ShipperDropped())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package logger
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog"
+)
+
+// HandleSignals changes the global level on SIGUSR1 and SIGUSR2 until ctx
+// is done. SIGUSR1 toggles between debug and the configured level, SIGUSR2
+// restores the configured levels.
+func HandleSignals(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				switch sig {
+				case syscall.SIGUSR1:
+					if GetLevel() == zerolog.DebugLevel {
+						setLevel(zerolog.Level(configured.Load()), "signal")
+					} else {
+						setLevel(zerolog.DebugLevel, "signal")
+					}
+				case syscall.SIGUSR2:
+					resetLevels("signal")
+				}
+			}
+		}
+	}()
+}
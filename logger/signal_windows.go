@@ -0,0 +1,6 @@
+package logger
+
+import "context"
+
+// HandleSignals is a no-op on Windows, which has no SIGUSR1/SIGUSR2
+func HandleSignals(ctx context.Context) {}
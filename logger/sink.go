@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Dankko0w0/gospike/models"
+)
+
+// SinkFactory creates an output from its configuration. Writers that also
+// implement io.Closer are closed when the configuration is replaced, and
+// writers implementing zerolog.LevelWriter receive the event level.
+type SinkFactory func(cfg models.SinkConfig) (io.Writer, error)
+
+var (
+	sinksMu sync.RWMutex
+	sinks   = map[string]SinkFactory{
+		"syslog":   newSyslogSink,
+		"rfc5424":  newRFC5424Sink,
+		"journald": newJournaldSink,
+		"tcp-json": newShipperSink,
+	}
+)
+
+// RegisterSink makes a sink type available to LoggerConfig.Sinks
+func RegisterSink(name string, factory SinkFactory) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks[name] = factory
+}
+
+// Sinks returns the names of the registered sink types
+func Sinks() []string {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newSink(cfg models.SinkConfig) (io.Writer, error) {
+	sinksMu.RLock()
+	factory, ok := sinks[cfg.Type]
+	sinksMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown log sink %q", cfg.Type)
+	}
+
+	w, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log sink %q: %w", cfg.Type, err)
+	}
+	return w, nil
+}
+
+// sinkOptions returns the string values of cfg.Options, rejecting keys not
+// in known so that typos in the configuration are reported
+func sinkOptions(cfg models.SinkConfig, known ...string) (map[string]string, error) {
+	opts := make(map[string]string, len(cfg.Options))
+	for key, value := range cfg.Options {
+		if !slices.Contains(known, key) {
+			if len(known) == 0 {
+				return nil, fmt.Errorf("sink does not take options, got %q", key)
+			}
+			return nil, fmt.Errorf("unknown option %q, expected one of %s", key, strings.Join(known, ", "))
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("option %q must be a string, got %T", key, value)
+		}
+		opts[key] = s
+	}
+	return opts, nil
+}
@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SlogHandler is an slog.Handler that writes through this package, so slog
+// records share the outputs, levels and format of the logger
+type SlogHandler struct {
+	module string
+	groups []slogGroup // groups[0] holds the top-level attributes
+}
+
+type slogGroup struct {
+	name  string
+	attrs []slog.Attr
+}
+
+// NewSlogHandler returns an slog.Handler backed by the global logger, or
+// by Module(module) when module is not empty
+func NewSlogHandler(module string) *SlogHandler {
+	return &SlogHandler{module: module, groups: []slogGroup{{}}}
+}
+
+// Slog returns an *slog.Logger backed by the global logger
+func Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(""))
+}
+
+// SetSlogDefault makes the global logger the backend of slog's default logger
+func SetSlogDefault() {
+	slog.SetDefault(Slog())
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return zerologLevel(level) >= effectiveLevel(h.module)
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	l := Logger()
+	if h.module != "" {
+		l = Module(h.module)
+	}
+	l = Enrich(ctx, l)
+
+	groups := make([]slogGroup, len(h.groups))
+	copy(groups, h.groups)
+	last := &groups[len(groups)-1]
+	last.attrs = append(last.attrs[:len(last.attrs):len(last.attrs)], recordAttrs(r)...)
+
+	// 由内向外构造嵌套分组, 没有属性的分组省略
+	var inner *zerolog.Event
+	for i := len(groups) - 1; i >= 1; i-- {
+		if len(groups[i].attrs) == 0 && inner == nil {
+			continue
+		}
+		d := appendAttrs(zerolog.Dict(), groups[i].attrs)
+		if inner != nil {
+			d = d.Dict(groups[i+1].name, inner)
+		}
+		inner = d
+	}
+
+	e := appendAttrs(l.WithLevel(zerologLevel(r.Level)), groups[0].attrs)
+	if inner != nil {
+		e = e.Dict(groups[1].name, inner)
+	}
+	e.Msg(r.Message)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := h.clone()
+	last := &h2.groups[len(h2.groups)-1]
+	last.attrs = append(last.attrs[:len(last.attrs):len(last.attrs)], attrs...)
+	return h2
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := h.clone()
+	h2.groups = append(h2.groups, slogGroup{name: name})
+	return h2
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	groups := make([]slogGroup, len(h.groups))
+	copy(groups, h.groups)
+	return &SlogHandler{module: h.module, groups: groups}
+}
+
+func recordAttrs(r slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// appendAttrs adds slog attributes to a zerolog event or dictionary
+func appendAttrs(e *zerolog.Event, attrs []slog.Attr) *zerolog.Event {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+
+		switch a.Value.Kind() {
+		case slog.KindGroup:
+			group := a.Value.Group()
+			if len(group) == 0 {
+				continue
+			}
+			if a.Key == "" {
+				e = appendAttrs(e, group)
+			} else {
+				e = e.Dict(a.Key, appendAttrs(zerolog.Dict(), group))
+			}
+		case slog.KindString:
+			e = e.Str(a.Key, a.Value.String())
+		case slog.KindInt64:
+			e = e.Int64(a.Key, a.Value.Int64())
+		case slog.KindUint64:
+			e = e.Uint64(a.Key, a.Value.Uint64())
+		case slog.KindFloat64:
+			e = e.Float64(a.Key, a.Value.Float64())
+		case slog.KindBool:
+			e = e.Bool(a.Key, a.Value.Bool())
+		case slog.KindDuration:
+			e = e.Dur(a.Key, a.Value.Duration())
+		case slog.KindTime:
+			e = e.Time(a.Key, a.Value.Time())
+		default:
+			if err, ok := a.Value.Any().(error); ok {
+				e = e.AnErr(a.Key, err)
+			} else {
+				e = e.Interface(a.Key, a.Value.Any())
+			}
+		}
+	}
+	return e
+}
+
+// zerologLevel maps an slog level to the closest zerolog level
+func zerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
+
+// slogLevel maps a zerolog level to an slog level
+func slogLevel(level zerolog.Level) slog.Level {
+	switch level {
+	case zerolog.TraceLevel:
+		return slog.LevelDebug - 4
+	case zerolog.DebugLevel:
+		return slog.LevelDebug
+	case zerolog.WarnLevel:
+		return slog.LevelWarn
+	case zerolog.ErrorLevel:
+		return slog.LevelError
+	case zerolog.FatalLevel, zerolog.PanicLevel:
+		return slog.LevelError + 4
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// UseSlogHandler replaces the configured outputs with h: every event is
+// decoded and passed to h as an slog record. Levels, sampling,
+// deduplication, redaction and asynchronous writing still apply as
+// configured; InitFromConfig restores regular outputs.
+func UseSlogHandler(h slog.Handler) {
+	ww := currentWrapping()
+	output.swap(wrapWriter(slogWriter{h: h}, nil, ww.redactor, ww.async))
+}
+
+// slogWriter converts JSON events into slog records
+type slogWriter struct {
+	h slog.Handler
+}
+
+func (w slogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w slogWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	ctx := context.Background()
+	if !w.h.Enabled(ctx, slogLevel(level)) {
+		return len(p), nil
+	}
+
+	// UseNumber 避免大整数 (如 int64 ID) 转为 float64 丢失精度
+	var fields map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&fields); err != nil {
+		return 0, err
+	}
+
+	t := time.Now()
+	if v, ok := fields[zerolog.TimestampFieldName].(string); ok {
+		if parsed, err := time.Parse(zerolog.TimeFieldFormat, v); err == nil {
+			t = parsed
+		}
+	}
+	msg, _ := fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.TimestampFieldName)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.LevelFieldName)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	r := slog.NewRecord(t, slogLevel(level), msg, 0)
+	for _, k := range keys {
+		r.AddAttrs(slogAttr(k, fields[k]))
+	}
+	if err := w.h.Handle(ctx, r); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// slogAttr converts a decoded field, keeping integers as int64 or uint64
+func slogAttr(key string, v interface{}) slog.Attr {
+	n, ok := v.(json.Number)
+	if !ok {
+		return slog.Any(key, v)
+	}
+	if i, err := n.Int64(); err == nil {
+		return slog.Int64(key, i)
+	}
+	if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return slog.Uint64(key, u)
+	}
+	if f, err := n.Float64(); err == nil {
+		return slog.Float64(key, f)
+	}
+	return slog.String(key, n.String())
+}
@@ -0,0 +1,27 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestUseSlogHandlerRedactsAndKeepsIntegers(t *testing.T) {
+	ReplaceForTest(t)
+	var buf bytes.Buffer
+	UseSlogHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	defer Close(context.Background())
+
+	l := Logger()
+	l.Info().Str("password", "hunter2").Int64("id", 9007199254740993).Msg("login")
+
+	out := buf.String()
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("password was not redacted: %s", out)
+	}
+	if !strings.Contains(out, `"id":9007199254740993`) {
+		t.Errorf("id lost precision: %s", out)
+	}
+}
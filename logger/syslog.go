@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+	"github.com/rs/zerolog"
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogFormat formats one message for the wire
+type syslogFormat func(w *syslogWriter, priority int, msg []byte) []byte
+
+// syslogWriter sends each event to a syslog daemon, redialing once when a
+// write fails
+type syslogWriter struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string
+	msgID    string // RFC 5424 MSGID
+	sdID     string // RFC 5424 SD-ID for event fields, empty to send the raw event as MSG
+	format   syslogFormat
+	conn     net.Conn
+}
+
+// newSyslogSink writes to the local syslog daemon over its unix socket
+func newSyslogSink(cfg models.SinkConfig) (io.Writer, error) {
+	if cfg.Network == "" {
+		cfg.Network = "unixgram"
+	}
+	if cfg.Address == "" {
+		cfg.Address = localSyslogSocket()
+	}
+	if _, err := sinkOptions(cfg); err != nil {
+		return nil, err
+	}
+	return newSyslogWriter(cfg, formatLocal)
+}
+
+// newRFC5424Sink writes RFC 5424 messages to a remote collector over UDP
+// or TCP; TCP messages use octet counting framing (RFC 6587).
+//
+// Options: "msgid" sets MSGID. "sdid" (e.g. "fields@32473") moves the event
+// fields into a structured data element with that SD-ID and sends only the
+// message text as MSG; without it MSG is the raw event.
+func newRFC5424Sink(cfg models.SinkConfig) (io.Writer, error) {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	opts, err := sinkOptions(cfg, "msgid", "sdid")
+	if err != nil {
+		return nil, err
+	}
+	if id := opts["msgid"]; id != "" && !validSDName(id) {
+		return nil, fmt.Errorf("invalid msgid %q", id)
+	}
+	if id := opts["sdid"]; id != "" && !validSDName(id) {
+		return nil, fmt.Errorf("invalid sdid %q", id)
+	}
+
+	w, err := newSyslogWriter(cfg, formatRFC5424)
+	if err != nil {
+		return nil, err
+	}
+	w.msgID, w.sdID = opts["msgid"], opts["sdid"]
+	return w, nil
+}
+
+func newSyslogWriter(cfg models.SinkConfig, format syslogFormat) (*syslogWriter, error) {
+	facility := facilities["user"]
+	if cfg.Facility != "" {
+		f, ok := facilities[strings.ToLower(cfg.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+		}
+		facility = f
+	}
+
+	tag := cfg.Tag
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	w := &syslogWriter{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		facility: facility,
+		tag:      tag,
+		hostname: hostname,
+		format:   format,
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func localSyslogSocket() string {
+	for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return "/dev/log"
+}
+
+func (w *syslogWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *syslogWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	msg := w.format(w, w.facility*8+severity(level), bytes.TrimRight(p, "\n"))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// formatLocal formats a message for the local daemon: <PRI>TIMESTAMP TAG[PID]: MSG
+func formatLocal(w *syslogWriter, priority int, msg []byte) []byte {
+	return fmt.Appendf(nil, "<%d>%s %s[%d]: %s\n",
+		priority, time.Now().Format(time.Stamp), w.tag, os.Getpid(), msg)
+}
+
+// formatRFC5424 formats: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+func formatRFC5424(w *syslogWriter, priority int, msg []byte) []byte {
+	msgID, sd := w.msgID, "-"
+	if msgID == "" {
+		msgID = "-"
+	}
+	if w.sdID != "" {
+		if fields, text, ok := eventFields(msg); ok {
+			sd, msg = structuredData(w.sdID, fields), []byte(text)
+		}
+	}
+	line := fmt.Appendf(nil, "<%d>1 %s %s %s %d %s %s %s",
+		priority, time.Now().Format(time.RFC3339Nano), w.hostname, w.tag, os.Getpid(), msgID, sd, msg)
+	if strings.HasPrefix(w.network, "tcp") {
+		return append(strconv.AppendInt(nil, int64(len(line)), 10), append([]byte{' '}, line...)...)
+	}
+	return line
+}
+
+// eventFields decodes a JSON event into its fields, without the level and
+// message, and its message text
+func eventFields(event []byte) (map[string]interface{}, string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(event))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, "", false
+	}
+	text, _ := fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.LevelFieldName)
+	return fields, text, true
+}
+
+// fieldText returns a field value as text: strings as is, everything else as JSON
+func fieldText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// structuredData formats fields as one SD-ELEMENT, sorted by name. Names
+// that are not valid SD-NAMEs are skipped.
+func structuredData(id string, fields map[string]interface{}) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if validSDName(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("[" + id)
+	for _, name := range names {
+		b.WriteString(" " + name + `="`)
+		sdEscaper.WriteString(&b, fieldText(fields[name]))
+		b.WriteString(`"`)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// sdEscaper escapes PARAM-VALUE characters (RFC 5424 section 6.3.3)
+var sdEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, "]", `\]`)
+
+// validSDName reports whether s is a valid SD-NAME: 1 to 32 printable
+// US-ASCII characters except '=', ' ', ']' and '"'
+func validSDName(s string) bool {
+	if s == "" || len(s) > 32 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			return false
+		}
+	}
+	return true
+}
+
+// severity maps a zerolog level to a syslog severity
+func severity(level zerolog.Level) int {
+	switch level {
+	case zerolog.PanicLevel:
+		return 0 // emerg
+	case zerolog.FatalLevel:
+		return 2 // crit
+	case zerolog.ErrorLevel:
+		return 3 // err
+	case zerolog.WarnLevel:
+		return 4 // warning
+	case zerolog.InfoLevel, zerolog.NoLevel:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
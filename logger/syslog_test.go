@@ -0,0 +1,281 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dankko0w0/gospike/models"
+	"github.com/rs/zerolog"
+)
+
+// listenUnixgram returns a datagram socket in a temporary directory
+func listenUnixgram(t *testing.T) *net.UnixConn {
+	t.Helper()
+	// unix socket paths are limited to about 100 bytes, t.TempDir may be longer
+	dir, err := os.MkdirTemp("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "log.sock"), Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readPacket reads one datagram with a timeout
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func newTestSink(t *testing.T, cfg models.SinkConfig) zerolog.LevelWriter {
+	t.Helper()
+	w, err := newSink(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.(io.Closer).Close() })
+	return w.(zerolog.LevelWriter)
+}
+
+func TestSyslogSinkUnixgram(t *testing.T) {
+	conn := listenUnixgram(t)
+	w := newTestSink(t, models.SinkConfig{
+		Type: "syslog", Address: conn.LocalAddr().String(), Tag: "app", Facility: "local0",
+	})
+
+	event := `{"level":"warn","message":"disk low"}`
+	if _, err := w.WriteLevel(zerolog.WarnLevel, []byte(event+"\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readPacket(t, conn)
+	// local0 (16) * 8 + warning (4)
+	if !strings.HasPrefix(got, "<132>") {
+		t.Errorf("priority: got %q, want prefix <132>", got)
+	}
+	if want := fmt.Sprintf(" app[%d]: %s\n", os.Getpid(), event); !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, want suffix %q", got, want)
+	}
+}
+
+// parseRFC5424 splits a message into its header fields, structured data and MSG
+func parseRFC5424(t *testing.T, line string) (header []string, sd, msg string) {
+	t.Helper()
+	header = strings.SplitN(line, " ", 7)
+	if len(header) != 7 {
+		t.Fatalf("malformed message %q", line)
+	}
+	rest := header[6]
+	header = header[:6]
+	if strings.HasPrefix(rest, "-") {
+		return header, "-", strings.TrimPrefix(rest, "- ")
+	}
+	// 结构化数据以未转义的 ] 结束
+	for i := 1; i < len(rest); i++ {
+		if rest[i] == '\\' {
+			i++
+			continue
+		}
+		if rest[i] == ']' {
+			return header, rest[:i+1], strings.TrimPrefix(rest[i+1:], " ")
+		}
+	}
+	t.Fatalf("unterminated structured data in %q", line)
+	return
+}
+
+func TestRFC5424SinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w := newTestSink(t, models.SinkConfig{
+		Type: "rfc5424", Network: "udp", Address: conn.LocalAddr().String(),
+		Tag: "api", Facility: "daemon",
+		Options: map[string]interface{}{"msgid": "access", "sdid": "fields@32473"},
+	})
+	event := `{"level":"error","request_id":"abc","status":500,"path":"/a\"b]","message":"request failed"}`
+	if _, err := w.WriteLevel(zerolog.ErrorLevel, []byte(event+"\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	header, sd, msg := parseRFC5424(t, readPacket(t, conn))
+	// daemon (3) * 8 + err (3)
+	if header[0] != "<27>1" {
+		t.Errorf("PRI and VERSION: got %q, want <27>1", header[0])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, header[1]); err != nil {
+		t.Errorf("TIMESTAMP %q: %v", header[1], err)
+	}
+	if header[3] != "api" || header[4] != strconv.Itoa(os.Getpid()) || header[5] != "access" {
+		t.Errorf("APP-NAME PROCID MSGID: got %q", header[3:])
+	}
+	if want := `[fields@32473 path="/a\"b\]" request_id="abc" status="500"]`; sd != want {
+		t.Errorf("structured data:\n got %s\nwant %s", sd, want)
+	}
+	if msg != "request failed" {
+		t.Errorf("MSG: got %q", msg)
+	}
+}
+
+func TestRFC5424SinkTCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	w := newTestSink(t, models.SinkConfig{Type: "rfc5424", Network: "tcp", Address: ln.Addr().String(), Tag: "api"})
+	events := []string{`{"level":"info","message":"one"}`, `{"level":"debug","message":"two two"}`}
+	w.WriteLevel(zerolog.InfoLevel, []byte(events[0]+"\n"))
+	w.WriteLevel(zerolog.DebugLevel, []byte(events[1]+"\n"))
+
+	var conn net.Conn
+	select {
+	case conn = <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sink did not connect")
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	// user (1) * 8 + info (6), user * 8 + debug (7)
+	for i, pri := range []string{"<14>1", "<15>1"} {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+		if err != nil {
+			t.Fatalf("frame length %q: %v", length, err)
+		}
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			t.Fatal(err)
+		}
+		header, sd, msg := parseRFC5424(t, string(frame))
+		if header[0] != pri || header[5] != "-" || sd != "-" {
+			t.Errorf("frame %d: header %q, sd %q", i, header, sd)
+		}
+		if msg != events[i] {
+			t.Errorf("frame %d: MSG %q, want the raw event %q", i, msg, events[i])
+		}
+	}
+}
+
+// parseJournal decodes the journald native protocol
+func parseJournal(t *testing.T, data string) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for data != "" {
+		line, rest, _ := strings.Cut(data, "\n")
+		if key, value, ok := strings.Cut(line, "="); ok {
+			fields[key] = value
+			data = rest
+			continue
+		}
+		// 二进制格式: KEY\n<长度><值>\n
+		n := binary.LittleEndian.Uint64([]byte(rest[:8]))
+		fields[line] = rest[8 : 8+n]
+		data = rest[8+n+1:]
+	}
+	return fields
+}
+
+func TestJournaldSink(t *testing.T) {
+	conn := listenUnixgram(t)
+	w := newTestSink(t, models.SinkConfig{Type: "journald", Address: conn.LocalAddr().String(), Tag: "worker"})
+
+	event := `{"level":"error","message":"job failed","request_id":"abc","error":"line1\nline2","_hidden":1}`
+	if _, err := w.WriteLevel(zerolog.ErrorLevel, []byte(event+"\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := parseJournal(t, readPacket(t, conn))
+	want := map[string]string{
+		"MESSAGE":           "job failed",
+		"PRIORITY":          "3",
+		"SYSLOG_FACILITY":   "1",
+		"SYSLOG_IDENTIFIER": "worker",
+		"REQUEST_ID":        "abc",
+		"ERROR":             "line1\nline2",
+		"HIDDEN":            "1",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got fields %q, want %q", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+func TestJournaldSinkPlainText(t *testing.T) {
+	conn := listenUnixgram(t)
+	w := newTestSink(t, models.SinkConfig{Type: "journald", Address: conn.LocalAddr().String(), Tag: "worker"})
+	w.Write([]byte("not json\n"))
+
+	got := parseJournal(t, readPacket(t, conn))
+	if got["MESSAGE"] != "not json" || got["PRIORITY"] != "6" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSinkOptionsValidated(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	addr := conn.LocalAddr().String()
+
+	for _, cfg := range []models.SinkConfig{
+		{Type: "rfc5424", Address: addr, Options: map[string]interface{}{"sd_id": "x"}},
+		{Type: "rfc5424", Address: addr, Options: map[string]interface{}{"msgid": 1}},
+		{Type: "rfc5424", Address: addr, Options: map[string]interface{}{"sdid": "has space"}},
+		{Type: "tcp-json", Address: addr, Options: map[string]interface{}{"msgid": "x"}},
+	} {
+		if w, err := newSink(cfg); err == nil {
+			w.(io.Closer).Close()
+			t.Errorf("%s with options %v: expected an error", cfg.Type, cfg.Options)
+		}
+	}
+}
+
+func TestStructuredDataSkipsInvalidNames(t *testing.T) {
+	got := structuredData("x@1", map[string]interface{}{"ok": "v", "bad name": "v", "a=b": "v"})
+	if want := `[x@1 ok="v"]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
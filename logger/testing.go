@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// TestWriter returns a writer that formats events for the console and
+// logs them through t, so they appear under the test that produced them
+// with go test -v
+func TestWriter(t testing.TB) io.Writer {
+	return zerolog.ConsoleWriter{Out: tbWriter{t}, NoColor: true, TimeFormat: "15:04:05"}
+}
+
+type tbWriter struct {
+	t testing.TB
+}
+
+func (w tbWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// ReplaceForTest sends all events to writers, or to TestWriter(t) when
+// none are given, with every level enabled and sampling disabled. The
+// previous configuration is restored when the test finishes. Tests that
+// call it must not run in parallel.
+func ReplaceForTest(t testing.TB, writers ...io.Writer) {
+	t.Helper()
+	if len(writers) == 0 {
+		writers = []io.Writer{TestWriter(t)}
+	}
+
+	w, closers := output.take(zerolog.MultiLevelWriter(writers...), nil)
+
+	prevLevel := GetLevel()
+	prevConfigured := configured.Load()
+	levelsMu.Lock()
+	prevModules, prevConfiguredModules := moduleLevels, configuredModules
+	moduleLevels, configuredModules = map[string]zerolog.Level{}, map[string]zerolog.Level{}
+	levelsMu.Unlock()
+	level.Store(int32(zerolog.TraceLevel))
+
+	prevSampler := sampling.current.Swap(nil)
+	prevDedup := dedup.Swap(nil)
+
+	t.Cleanup(func() {
+		output.take(w, closers)
+
+		level.Store(int32(prevLevel))
+		configured.Store(prevConfigured)
+		levelsMu.Lock()
+		moduleLevels, configuredModules = prevModules, prevConfiguredModules
+		levelsMu.Unlock()
+
+		sampling.current.Store(prevSampler)
+		dedup.Store(prevDedup)
+	})
+}
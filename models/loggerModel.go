@@ -6,21 +6,82 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// LoggerConfig 定义日志配置
 type LoggerConfig struct {
-	LogToConsole bool   `yaml:"logToConsole"`
-	LogToFile    bool   `yaml:"logToFile"`
-	LogFilePath  string `yaml:"logFilePath"`
-	MaxFileSize  int    `yaml:"maxFileSize"`
-	MaxBackups   int    `yaml:"maxBackups"`
-	MaxAge       int    `yaml:"maxAge"`
+	Level         string                    `yaml:"level" mapstructure:"level"`     // trace, debug, info, warn, error
+	Modules       map[string]string         `yaml:"modules" mapstructure:"modules"` // per-module level overrides
+	Format        string                    `yaml:"format" mapstructure:"format"`   // console, json
+	LogToConsole  bool                      `yaml:"logToConsole" mapstructure:"logToConsole"`
+	ConsoleLevel  string                    `yaml:"consoleLevel" mapstructure:"consoleLevel"`
+	ConsoleFormat *ConsoleFormat            `yaml:"consoleFormat" mapstructure:"consoleFormat"`
+	LogToFile     bool                      `yaml:"logToFile" mapstructure:"logToFile"`
+	FileLevel     string                    `yaml:"fileLevel" mapstructure:"fileLevel"`
+	LogFilePath   string                    `yaml:"logFilePath" mapstructure:"logFilePath"`
+	MaxFileSize   int                       `yaml:"maxFileSize" mapstructure:"maxFileSize"` // megabytes
+	MaxBackups    int                       `yaml:"maxBackups" mapstructure:"maxBackups"`
+	MaxAge        int                       `yaml:"maxAge" mapstructure:"maxAge"` // days
+	Compress      bool                      `yaml:"compress" mapstructure:"compress"`
+	Rotation      RotationConfig            `yaml:"rotation" mapstructure:"rotation"`
+	Redact        RedactConfig              `yaml:"redact" mapstructure:"redact"`
+	Sinks         []SinkConfig              `yaml:"sinks" mapstructure:"sinks"`
+	Async         AsyncConfig               `yaml:"async" mapstructure:"async"`
+	Sampling      map[string]SamplingConfig `yaml:"sampling" mapstructure:"sampling"` // 按级别采样, 如 debug, info
+	Dedup         DedupConfig               `yaml:"dedup" mapstructure:"dedup"`
+}
+
+// SamplingConfig 定义单个级别的采样: 每个周期内先记录 Burst 条, 之后每 Every 条记录 1 条
+type SamplingConfig struct {
+	Burst  uint32        `yaml:"burst" mapstructure:"burst"`
+	Period time.Duration `yaml:"period" mapstructure:"period"`
+	Every  uint32        `yaml:"every" mapstructure:"every"`
+}
+
+// DedupConfig 定义重复日志合并配置
+type DedupConfig struct {
+	Enabled bool          `yaml:"enabled" mapstructure:"enabled"`
+	Window  time.Duration `yaml:"window" mapstructure:"window"`
+}
+
+// AsyncConfig 定义异步写入配置
+type AsyncConfig struct {
+	Enabled    bool   `yaml:"enabled" mapstructure:"enabled"`
+	BufferSize int    `yaml:"bufferSize" mapstructure:"bufferSize"` // 缓冲的日志条数
+	Policy     string `yaml:"policy" mapstructure:"policy"`         // block, drop_newest, drop_oldest
+}
+
+// SinkConfig 定义额外的日志输出
+type SinkConfig struct {
+	Type       string                 `yaml:"type" mapstructure:"type"` // syslog, rfc5424, journald, tcp-json 或自定义注册的类型
+	Level      string                 `yaml:"level" mapstructure:"level"`
+	Network    string                 `yaml:"network" mapstructure:"network"` // unixgram, udp, tcp
+	Address    string                 `yaml:"address" mapstructure:"address"`
+	Tag        string                 `yaml:"tag" mapstructure:"tag"`           // syslog APP-NAME
+	Facility   string                 `yaml:"facility" mapstructure:"facility"` // user, daemon, local0 ... local7
+	BufferSize int                    `yaml:"bufferSize" mapstructure:"bufferSize"`
+	Options    map[string]interface{} `yaml:"options" mapstructure:"options"` // 类型特定的选项, 如 rfc5424 的 msgid, sdid; 未知选项报错
+}
+
+// RotationConfig 定义按时间轮转和保留策略. 启用后 LogFilePath 为指向当前文件的符号链接,
+// 日志写入 <name>-<时间><ext>, 如 logs/app-2024-01-02.log
+type RotationConfig struct {
+	Interval     string `yaml:"interval" mapstructure:"interval"`         // daily, hourly
+	TimeFormat   string `yaml:"timeFormat" mapstructure:"timeFormat"`     // Go 时间格式, 默认按 Interval 选择
+	MaxTotalSize int    `yaml:"maxTotalSize" mapstructure:"maxTotalSize"` // 所有备份的总大小上限, megabytes
+}
+
+// RedactConfig 定义日志脱敏配置
+type RedactConfig struct {
+	Disabled bool     `yaml:"disabled" mapstructure:"disabled"`
+	Keys     []string `yaml:"keys" mapstructure:"keys"`         // 追加到默认字段名之后
+	Patterns []string `yaml:"patterns" mapstructure:"patterns"` // 追加到默认的 dsn, bearer 之后: email 或正则表达式
 }
 
 // ConsoleFormat 定义控制台输出格式的配置
 type ConsoleFormat struct {
-	TimeFormat   string
-	NoColor      bool
-	PartsOrder   []string
-	PartsExclude []string
+	TimeFormat   string   `yaml:"timeFormat" mapstructure:"timeFormat"`
+	NoColor      bool     `yaml:"noColor" mapstructure:"noColor"`
+	PartsOrder   []string `yaml:"partsOrder" mapstructure:"partsOrder"`
+	PartsExclude []string `yaml:"partsExclude" mapstructure:"partsExclude"`
 }
 
 // DefaultConsoleFormat 返回默认的控制台格式配置
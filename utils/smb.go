@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/hirochachacha/go-smb2"
 	"github.com/spf13/viper"
@@ -31,11 +32,10 @@ type SMBConfig struct {
 func NewSMBClient(config SMBConfig) (*SMBClient, error) {
 
 	// Establish a connection
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", config.Address, config.Port))
+	conn, err := net.Dial("tcp", net.JoinHostPort(config.Address, strconv.Itoa(config.Port)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to SMB server: %w", err)
 	}
-	defer conn.Close()
 
 	// Establish SMB dialer
 	dialer := &smb2.Dialer{
@@ -48,9 +48,9 @@ func NewSMBClient(config SMBConfig) (*SMBClient, error) {
 	// Connect to the shared folder
 	session, err := dialer.Dial(conn)
 	if err != nil {
-		panic(err)
+		conn.Close()
+		return nil, fmt.Errorf("failed to establish SMB session: %w", err)
 	}
-	defer session.Logoff()
 
 	return &SMBClient{
 		Connection: conn,
@@ -96,7 +96,7 @@ func (c *SMBClient) UploadFile(localPath, remotePath string) error {
 
 	fs, err := c.Session.Mount(remoteDir)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to mount share: %w", err)
 	}
 	defer fs.Umount()
 